@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/go-chi/chi/v5"
+)
+
+type Admin struct {
+	AdminUsecase domainAPI.AdminUsecase
+	Cfg          *config.Config
+}
+
+type adjustFunc func(ctx context.Context, adminID int, username string, request *domainAPI.CoinAdjustmentRequest) (*domainAPI.BalanceResponse, error)
+
+func (admin *Admin) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+
+	var request domainAPI.SetRolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.ValidateRoles(); err != nil {
+		slog.Info("Invalid roles", slog.Any("roles", request.Roles))
+		writeDomainError(w, err)
+		return
+	}
+
+	response, err := admin.AdminUsecase.SetUserRoles(r.Context(), username, request.Roles)
+	if err != nil {
+		slog.Info("Failed to set user roles", slog.String("username", username))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+	slog.Info("User roles updated", slog.String("username", username), slog.Any("roles", response.Roles))
+}
+
+func (admin *Admin) GrantCoins(w http.ResponseWriter, r *http.Request) {
+	admin.adjustCoins(w, r, admin.AdminUsecase.GrantCoins)
+}
+
+func (admin *Admin) DeductCoins(w http.ResponseWriter, r *http.Request) {
+	admin.adjustCoins(w, r, admin.AdminUsecase.DeductCoins)
+}
+
+func (admin *Admin) adjustCoins(w http.ResponseWriter, r *http.Request, adjust adjustFunc) {
+	ctx := r.Context()
+	adminID := domain.MustPrincipal(ctx).UserID
+	username := chi.URLParam(r, "username")
+
+	var request domainAPI.CoinAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.ValidateAmount(); err != nil {
+		slog.Info("Invalid amount", slog.Int("amount", request.Amount))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid amount")
+		return
+	}
+
+	if err := request.ValidateReason(); err != nil {
+		slog.Info("Invalid reason", slog.String("username", username))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid reason")
+		return
+	}
+
+	response, err := adjust(ctx, adminID, username, &request)
+	if err != nil {
+		slog.Info("Failed to adjust balance", slog.String("username", username), slog.Int("adminID", adminID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+	slog.Info("Balance adjusted", slog.String("username", username), slog.Int("adminID", adminID), slog.Int("coins", response.Coins))
+}
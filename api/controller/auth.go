@@ -1,11 +1,15 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
 	"github.com/eslupmi101/avito_merch_store/internal/utility"
 )
@@ -15,27 +19,52 @@ type Auth struct {
 	Cfg         *config.Config
 }
 
-func (auth *Auth) Authentication(w http.ResponseWriter, r *http.Request) {
-	var request domainAPI.AuthRequest
+type authenticateFunc func(ctx context.Context, username, password string) (*domain.User, error)
 
+// Authentication logs existing users in and, unless the service runs in
+// login mode, registers unknown usernames on the fly.
+func (auth *Auth) Authentication(w http.ResponseWriter, r *http.Request) {
 	slog.Info("Received authentication request")
 
+	authenticate := auth.AuthUsecase.GetOrCreateByUsernamePassword
+	if auth.Cfg.Auth.Mode == config.AuthModeLogin {
+		authenticate = auth.AuthUsecase.Login
+	}
+
+	auth.authenticate(w, r, authenticate, http.StatusOK)
+}
+
+func (auth *Auth) Login(w http.ResponseWriter, r *http.Request) {
+	slog.Info("Received login request")
+
+	auth.authenticate(w, r, auth.AuthUsecase.Login, http.StatusOK)
+}
+
+func (auth *Auth) Register(w http.ResponseWriter, r *http.Request) {
+	slog.Info("Received registration request")
+
+	auth.authenticate(w, r, auth.AuthUsecase.Register, http.StatusCreated)
+}
+
+func (auth *Auth) authenticate(w http.ResponseWriter, r *http.Request, authenticate authenticateFunc, successStatus int) {
+	var request domainAPI.AuthRequest
+
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
-		http.Error(w, utility.JsonError("Invalid body/json"), http.StatusBadRequest)
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
 		return
 	}
 
 	if err := request.ValidateUsername(); err != nil {
 		slog.Warn("Invalid username format", slog.String("username", request.Username))
-		http.Error(w, utility.JsonError("Invalid username"), http.StatusBadRequest)
+		utility.WriteError(w, http.StatusBadRequest, "Invalid username")
 		return
 	}
 
 	if err := request.ValidatePassword(); err != nil {
 		slog.Warn("Invalid password format", slog.String("password", request.Username))
-		http.Error(w, utility.JsonError("Invalid password"), http.StatusBadRequest)
+		utility.WriteError(w, http.StatusBadRequest, "Invalid password")
 		return
 	}
 
@@ -44,28 +73,75 @@ func (auth *Auth) Authentication(w http.ResponseWriter, r *http.Request) {
 		slog.String("username", request.Username),
 	)
 
-	user, err := auth.AuthUsecase.GetOrCreateByUsernamePassword(r.Context(), request.Username, request.Password)
+	user, err := authenticate(r.Context(), request.Username, request.Password)
 	if err != nil {
-		slog.Error("User not authorized or lost connection", slog.String("error", err.Error()))
-		http.Error(w, utility.JsonError("User not authorized"), http.StatusUnauthorized)
+		slog.Info("User not authorized", slog.String("username", request.Username))
+		writeDomainError(w, err)
 		return
 	}
 
-	token, err := auth.AuthUsecase.CreateToken(user.ID, auth.Cfg.SecretKey)
+	authResponseData, err := auth.AuthUsecase.CreateTokens(r.Context(), user)
 	if err != nil {
 		slog.Error("Failed to create token", slog.Int("userID", user.ID), slog.String("error", err.Error()))
-		http.Error(w, utility.JsonError("Internal server error"), http.StatusInternalServerError)
+		utility.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 	slog.Info("Token generated successfully", slog.Int("userID", user.ID))
 
-	authResponseData := domainAPI.AuthResponse{
-		Token: token,
+	utility.WriteJSON(w, successStatus, authResponseData)
+
+	slog.Info("Authentication successful", slog.Int("userID", user.ID))
+}
+
+func (auth *Auth) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request domainAPI.RefreshRequest
+
+	slog.Info("Received token refresh request")
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if request.RefreshToken == "" {
+		utility.WriteError(w, http.StatusBadRequest, "Invalid refresh token")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(authResponseData)
+	authResponseData, err := auth.AuthUsecase.Refresh(r.Context(), request.RefreshToken)
+	if err != nil {
+		slog.Info("Failed to refresh token")
+		writeDomainError(w, err)
+		return
+	}
 
-	slog.Info("Authentication successful", slog.Int("userID", user.ID))
+	utility.WriteJSON(w, http.StatusOK, authResponseData)
+
+	slog.Info("Token refreshed successfully")
+}
+
+func (auth *Auth) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal := domain.MustPrincipal(ctx)
+	userID := principal.UserID
+
+	// The body is optional: an empty one logs out of the current session only.
+	var request domainAPI.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := auth.AuthUsecase.Logout(ctx, userID, principal.TokenID, request.AllSessions); err != nil {
+		slog.Info("Failed to logout", slog.Int("userID", userID))
+		writeDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	slog.Info("Logout successful", slog.Int("userID", userID), slog.Bool("allSessions", request.AllSessions))
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
 	"github.com/eslupmi101/avito_merch_store/internal/utility"
 )
@@ -18,44 +19,31 @@ type CoinSender struct {
 func (cs *CoinSender) CoinSender(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	values, ok := ctx.Value(config.AuthMiddlewareValuesKey).(map[string]interface{})
-	if !ok {
-		slog.Error("Cannot retrieve middleware values from context")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	isAuthorizedAny, _ := values["isAuthorized"].(bool)
-	if !isAuthorizedAny {
-		slog.Error("User not authorized", slog.Bool("bool", values["isAuthorized"].(bool)))
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID, ok := values["userID"].(int)
-	if !ok {
-		slog.Error("UserID not found")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	userID := domain.MustPrincipal(ctx).UserID
 
 	var request domainAPI.CoinSenderRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
-		http.Error(w, utility.JsonError("Invalid body/json"), http.StatusBadRequest)
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
 		return
 	}
 
 	if err := request.ValidateToUser(); err != nil {
 		slog.Info("Validation toUser failed", slog.String("error", err.Error()))
-		http.Error(w, "Invalid ToUser", http.StatusBadRequest)
+		utility.WriteError(w, http.StatusBadRequest, "Invalid ToUser")
 		return
 	}
 
 	if err := request.ValidateAmount(); err != nil {
 		slog.Info("Validation amount failed", slog.String("error", err.Error()))
-		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		utility.WriteError(w, http.StatusBadRequest, "Invalid amount")
+		return
+	}
+
+	if err := request.ValidateMessage(); err != nil {
+		slog.Info("Validation message failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid message")
 		return
 	}
 
@@ -64,23 +52,52 @@ func (cs *CoinSender) CoinSender(w http.ResponseWriter, r *http.Request) {
 		slog.String("username", request.ToUser),
 	)
 
-	if err := cs.CoinSenderUsecase.SendCoinToUser(ctx, userID, request.ToUser, request.Amount); err != nil {
-		switch err.Error() {
-		case "insufficient funds":
-			slog.Info(".", slog.Int("userID", userID))
-			http.Error(w, "Insufficient funds", http.StatusBadRequest)
+	if err := cs.CoinSenderUsecase.SendCoinToUser(ctx, userID, request.ToUser, request.Amount, request.Message); err != nil {
+		slog.Info("Failed to send coin.", slog.Int("userID", userID), slog.String("ToUser", request.ToUser))
+		writeDomainError(w, err)
+		return
+	}
 
-		case "toUser does not exist":
-			slog.Info("toUser does not exists.", slog.String("ToUser", request.ToUser))
-			http.Error(w, "toUser does not exists", http.StatusBadRequest)
+	w.WriteHeader(http.StatusOK)
+	slog.Info("Coin sent successfully", slog.Int("userID", userID), slog.String("ToUser", request.ToUser))
+}
+
+// Bulk sends coins to several users at once. The batch is all-or-nothing:
+// when it is rejected the response still lists every recipient, marking
+// the failed entry and the ones that were not sent because of it.
+func (cs *CoinSender) Bulk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	var request domainAPI.BulkSendRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		slog.Info("Validation bulk transfer failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid transfers: "+err.Error())
+		return
+	}
 
-		default:
-			slog.Error("Failed to send coin.", slog.Int("userID", userID), slog.String("ToUser", request.ToUser), slog.String("error", err.Error()))
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+	response, err := cs.CoinSenderUsecase.SendCoinsBulk(ctx, userID, &request)
+	if err != nil {
+		status, message, ok := lookupDomainError(err)
+		if !ok {
+			slog.Error("Failed to send bulk transfer", slog.Int("userID", userID), slog.String("error", err.Error()))
+			status, message = http.StatusInternalServerError, "Internal server error"
+		} else {
+			slog.Info("Bulk transfer rejected", slog.Int("userID", userID), slog.String("error", err.Error()))
 		}
+		// Every entry is reported, so the sender knows nobody was paid.
+		response.Errors = message
+		utility.WriteJSON(w, status, response)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	slog.Info("Coin sent successfully", slog.Int("userID", userID), slog.String("ToUser", request.ToUser))
+	utility.WriteJSON(w, http.StatusOK, response)
+	slog.Info("Bulk transfer sent", slog.Int("userID", userID), slog.Int("recipients", len(response.Results)), slog.Int("total", response.Total))
 }
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+// domainErrors maps errors returned by usecases to the response sent to the client.
+var domainErrors = []struct {
+	err     error
+	status  int
+	message string
+}{
+	{domain.ErrInsufficientFunds, http.StatusBadRequest, "Insufficient funds"},
+	{domain.ErrMerchNotFound, http.StatusBadRequest, "Merch does not exist"},
+	{domain.ErrRecipientNotFound, http.StatusBadRequest, "toUser does not exist"},
+	{domain.ErrSelfTransfer, http.StatusBadRequest, "Cannot send coins to yourself"},
+	{domain.ErrUserNotFound, http.StatusNotFound, "User not found"},
+	{domain.ErrInvalidCredentials, http.StatusUnauthorized, "User not authorized"},
+	{domain.ErrUserAlreadyExists, http.StatusConflict, "User already exists"},
+	{domain.ErrInvalidRefreshToken, http.StatusUnauthorized, "Invalid refresh token"},
+	{domain.ErrInvalidRole, http.StatusBadRequest, "Invalid role"},
+	{domain.ErrMerchAlreadyExists, http.StatusConflict, "Merch already exists"},
+	{domain.ErrOutOfStock, http.StatusBadRequest, "Merch is out of stock"},
+	{domain.ErrStockChange, http.StatusConflict, "Stock change does not apply to the current stock"},
+	{domain.ErrOrderNotFound, http.StatusNotFound, "Order not found"},
+	{domain.ErrOrderTransition, http.StatusConflict, "Order status does not allow this operation"},
+	{domain.ErrBalanceLimit, http.StatusBadRequest, "Balance limit exceeded"},
+	{domain.ErrTransactionNotFound, http.StatusNotFound, "Transaction not found"},
+	{domain.ErrScheduledTransferNotFound, http.StatusNotFound, "Scheduled transfer not found"},
+	{domain.ErrScheduledTransferNotActive, http.StatusConflict, "Scheduled transfer is not active"},
+}
+
+// writeDomainError responds with the status and message mapped to err,
+// falling back to 500 for errors unknown to the domain.
+func writeDomainError(w http.ResponseWriter, err error) {
+	if status, message, ok := lookupDomainError(err); ok {
+		slog.Info("Request rejected", slog.Int("status", status), slog.String("error", err.Error()))
+		utility.WriteError(w, status, message)
+		return
+	}
+
+	slog.Error("Request failed", slog.String("error", err.Error()))
+	utility.WriteError(w, http.StatusInternalServerError, "Internal server error")
+}
+
+// lookupDomainError returns the status and message mapped to err, if any.
+func lookupDomainError(err error) (int, string, bool) {
+	for _, de := range domainErrors {
+		if errors.Is(err, de.err) {
+			return de.status, de.message, true
+		}
+	}
+	return 0, "", false
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	utility.WriteError(w, http.StatusNotFound, "Not found")
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	utility.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+type JWKS struct {
+	TokenSigner domain.TokenSigner
+}
+
+// Keys publishes the public keys other services use to verify access tokens.
+func (jc *JWKS) Keys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utility.WriteJSON(w, http.StatusOK, jc.TokenSigner.PublicKeys())
+}
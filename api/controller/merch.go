@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/go-chi/chi/v5"
+)
+
+type Merch struct {
+	MerchUsecase domainAPI.MerchUsecase
+	Cfg          *config.Config
+}
+
+// Catalog lists the merch available for purchase.
+func (m *Merch) Catalog(w http.ResponseWriter, r *http.Request) {
+	catalog, err := m.MerchUsecase.GetCatalog(r.Context())
+	if err != nil {
+		slog.Info("Failed to get catalog")
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, catalog)
+}
+
+// List lists all merch, archived items included.
+func (m *Merch) List(w http.ResponseWriter, r *http.Request) {
+	merchList, err := m.MerchUsecase.List(r.Context())
+	if err != nil {
+		slog.Info("Failed to list merch")
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, merchList)
+}
+
+func (m *Merch) Create(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeMerchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := request.ValidateCreateStock(); err != nil {
+		slog.Info("Validation stock failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid stock")
+		return
+	}
+
+	merch, err := m.MerchUsecase.Create(r.Context(), request)
+	if err != nil {
+		slog.Info("Failed to create merch", slog.String("merchName", request.Name))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusCreated, merch)
+	slog.Info("Merch created", slog.Int("merchID", merch.ID), slog.String("merchName", merch.Name))
+}
+
+func (m *Merch) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := merchIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	request, ok := decodeMerchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	merch, err := m.MerchUsecase.Update(r.Context(), id, request)
+	if err != nil {
+		slog.Info("Failed to update merch", slog.Int("merchID", id))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, merch)
+	slog.Info("Merch updated", slog.Int("merchID", merch.ID))
+}
+
+func (m *Merch) Archive(w http.ResponseWriter, r *http.Request) {
+	id, ok := merchIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	merch, err := m.MerchUsecase.Archive(r.Context(), id)
+	if err != nil {
+		slog.Info("Failed to archive merch", slog.Int("merchID", id))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, merch)
+	slog.Info("Merch archived", slog.Int("merchID", merch.ID))
+}
+
+func merchIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		utility.WriteError(w, http.StatusBadRequest, "Invalid merch id")
+		return 0, false
+	}
+	return id, true
+}
+
+func decodeMerchRequest(w http.ResponseWriter, r *http.Request) (*domainAPI.MerchRequest, bool) {
+	var request domainAPI.MerchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return nil, false
+	}
+
+	if err := request.ValidateName(); err != nil {
+		slog.Info("Validation merch name failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid merch name")
+		return nil, false
+	}
+
+	if err := request.ValidatePrice(); err != nil {
+		slog.Info("Validation price failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid price")
+		return nil, false
+	}
+
+	if err := request.ValidateStock(); err != nil {
+		slog.Info("Validation stock failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid stock")
+		return nil, false
+	}
+
+	return &request, true
+}
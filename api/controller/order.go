@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/go-chi/chi/v5"
+)
+
+type Orders struct {
+	OrderUsecase domainAPI.OrderUsecase
+	Cfg          *config.Config
+}
+
+func (o *Orders) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	var request domainAPI.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		slog.Info("Validation order failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid order")
+		return
+	}
+
+	response, err := o.OrderUsecase.PlaceOrder(ctx, userID, &request)
+	if err != nil {
+		slog.Info("Failed to place order", slog.Int("userID", userID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusCreated, response)
+	slog.Info("Order placed", slog.Int("userID", userID), slog.Int("orderID", response.OrderID))
+}
+
+func (o *Orders) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	response, err := o.OrderUsecase.ListOrders(ctx, userID)
+	if err != nil {
+		slog.Info("Failed to list orders", slog.Int("userID", userID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+}
+
+// Cancel cancels an order the user placed and has not received yet.
+func (o *Orders) Cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	orderID, ok := orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := o.OrderUsecase.CancelOrder(ctx, userID, orderID)
+	if err != nil {
+		slog.Info("Failed to cancel order", slog.Int("userID", userID), slog.Int("orderID", orderID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, order)
+	slog.Info("Order cancelled", slog.Int("userID", userID), slog.Int("orderID", orderID))
+}
+
+// Pending lists the orders store staff still have to hand out.
+func (o *Orders) Pending(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := &domainAPI.PendingOrdersRequest{
+		Cursor: query.Get("cursor"),
+		Limit:  o.Cfg.History.PageSize,
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			utility.WriteError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		request.Limit = limit
+	}
+
+	if err := request.Validate(o.Cfg.History.MaxPageSize); err != nil {
+		slog.Info("Validation pending orders query failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	response, err := o.OrderUsecase.ListPendingOrders(r.Context(), request)
+	if err != nil {
+		slog.Info("Failed to list pending orders")
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+}
+
+func (o *Orders) Pick(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	staffID := domain.MustPrincipal(ctx).UserID
+
+	orderID, ok := orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := o.OrderUsecase.PickOrder(ctx, staffID, orderID)
+	if err != nil {
+		slog.Info("Failed to pick order", slog.Int("orderID", orderID), slog.Int("staffID", staffID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, order)
+	slog.Info("Order picked", slog.Int("orderID", orderID), slog.Int("staffID", staffID))
+}
+
+func (o *Orders) Deliver(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	staffID := domain.MustPrincipal(ctx).UserID
+
+	orderID, ok := orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var request domainAPI.DeliverRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		slog.Info("Validation delivery location failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid location")
+		return
+	}
+
+	order, err := o.OrderUsecase.DeliverOrder(ctx, staffID, orderID, &request)
+	if err != nil {
+		slog.Info("Failed to deliver order", slog.Int("orderID", orderID), slog.Int("staffID", staffID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, order)
+	slog.Info("Order delivered", slog.Int("orderID", orderID), slog.Int("staffID", staffID))
+}
+
+// Events returns the audit trail of an order.
+func (o *Orders) Events(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := o.OrderUsecase.GetOrderEvents(r.Context(), orderID)
+	if err != nil {
+		slog.Info("Failed to get order events", slog.Int("orderID", orderID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+}
+
+func (o *Orders) Refund(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adminID := domain.MustPrincipal(ctx).UserID
+
+	orderID, ok := orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := o.OrderUsecase.RefundOrder(ctx, adminID, orderID)
+	if err != nil {
+		slog.Info("Failed to refund order", slog.Int("orderID", orderID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, order)
+	slog.Info("Order refunded", slog.Int("orderID", orderID), slog.Int("adminID", adminID))
+}
+
+func orderIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		utility.WriteError(w, http.StatusBadRequest, "Invalid order id")
+		return 0, false
+	}
+	return id, true
+}
@@ -1,12 +1,13 @@
 package controller
 
 import (
-	"encoding/json"
 	"log/slog"
 	"net/http"
 
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
 )
 
 type Profile struct {
@@ -17,37 +18,16 @@ type Profile struct {
 func (prf *Profile) Profile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	values, ok := ctx.Value(config.AuthMiddlewareValuesKey).(map[string]interface{})
-	if !ok {
-		slog.Error("Cannot retrieve middleware values from context")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	isAuthorizedAny, _ := values["isAuthorized"].(bool)
-	if !isAuthorizedAny {
-		slog.Error("User not authorized", slog.Bool("bool", values["isAuthorized"].(bool)))
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID, ok := values["userID"].(int)
-	if !ok {
-		slog.Error("UserID not found")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	userID := domain.MustPrincipal(ctx).UserID
 
 	profileResponseData, err := prf.ProfileUsecase.GetProfile(ctx, userID)
 	if err != nil {
-		slog.Error("Failed to get profile", slog.Int("userID", userID), slog.String("error", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.Info("Failed to get profile", slog.Int("userID", userID))
+		writeDomainError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(profileResponseData)
+	utility.WriteJSON(w, http.StatusOK, profileResponseData)
 
 	slog.Info("Authentication successful", slog.Int("userID", userID))
 }
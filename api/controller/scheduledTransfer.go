@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/go-chi/chi/v5"
+)
+
+type ScheduledTransfers struct {
+	ScheduledTransferUsecase domainAPI.ScheduledTransferUsecase
+	Cfg                      *config.Config
+}
+
+func (st *ScheduledTransfers) Schedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	var request domainAPI.ScheduledTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.Validate(time.Now()); err != nil {
+		slog.Info("Validation scheduled transfer failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid scheduled transfer")
+		return
+	}
+
+	response, err := st.ScheduledTransferUsecase.Schedule(ctx, userID, &request)
+	if err != nil {
+		slog.Info("Failed to schedule transfer", slog.Int("userID", userID), slog.String("ToUser", request.ToUser))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusCreated, response)
+	slog.Info("Transfer scheduled", slog.Int("userID", userID), slog.Int("scheduledTransferID", response.ID))
+}
+
+func (st *ScheduledTransfers) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	response, err := st.ScheduledTransferUsecase.List(ctx, userID)
+	if err != nil {
+		slog.Info("Failed to list scheduled transfers", slog.Int("userID", userID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+}
+
+func (st *ScheduledTransfers) Cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	id, ok := scheduledTransferIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := st.ScheduledTransferUsecase.Cancel(ctx, userID, id)
+	if err != nil {
+		slog.Info("Failed to cancel scheduled transfer", slog.Int("userID", userID), slog.Int("scheduledTransferID", id))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+	slog.Info("Scheduled transfer cancelled", slog.Int("userID", userID), slog.Int("scheduledTransferID", id))
+}
+
+// Runs lists the attempts to execute a scheduled transfer, including the
+// reason each skipped run was skipped.
+func (st *ScheduledTransfers) Runs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	id, ok := scheduledTransferIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := st.ScheduledTransferUsecase.ListRuns(ctx, userID, id)
+	if err != nil {
+		slog.Info("Failed to list scheduled transfer runs", slog.Int("userID", userID), slog.Int("scheduledTransferID", id))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+}
+
+func scheduledTransferIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		utility.WriteError(w, http.StatusBadRequest, "Invalid scheduled transfer id")
+		return 0, false
+	}
+	return id, true
+}
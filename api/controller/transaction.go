@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/go-chi/chi/v5"
+)
+
+type Transactions struct {
+	TransactionUsecase domainAPI.TransactionUsecase
+	Cfg                *config.Config
+}
+
+// List returns the user's coin history page by page. Supported query
+// parameters are direction, counterparty, from, to (RFC 3339), cursor and limit.
+func (tc *Transactions) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	request, err := tc.parseQuery(r.URL.Query())
+	if err != nil {
+		slog.Info("Failed to parse transactions query", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	if err := request.Validate(tc.Cfg.History.MaxPageSize); err != nil {
+		slog.Info("Validation transactions query failed", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	response, err := tc.TransactionUsecase.ListTransactions(ctx, userID, request)
+	if err != nil {
+		slog.Info("Failed to list transactions", slog.Int("userID", userID))
+		writeDomainError(w, err)
+		return
+	}
+
+	utility.WriteJSON(w, http.StatusOK, response)
+}
+
+// React sets the reaction of the recipient on a received transfer.
+func (tc *Transactions) React(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := domain.MustPrincipal(ctx).UserID
+
+	transactionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || transactionID <= 0 {
+		utility.WriteError(w, http.StatusBadRequest, "Invalid transaction id")
+		return
+	}
+
+	var request domainAPI.ReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Warn("Failed to decode request body", slog.String("error", err.Error()))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		slog.Info("Invalid reaction", slog.String("reaction", request.Reaction))
+		utility.WriteError(w, http.StatusBadRequest, "Invalid reaction")
+		return
+	}
+
+	if err := tc.TransactionUsecase.React(ctx, userID, transactionID, request.Reaction); err != nil {
+		slog.Info("Failed to react to transaction", slog.Int("userID", userID), slog.Int("transactionID", transactionID))
+		writeDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	slog.Info("Reaction saved", slog.Int("userID", userID), slog.Int("transactionID", transactionID))
+}
+
+func (tc *Transactions) parseQuery(query url.Values) (*domainAPI.TransactionsRequest, error) {
+	request := &domainAPI.TransactionsRequest{
+		Direction:    query.Get("direction"),
+		Counterparty: query.Get("counterparty"),
+		Cursor:       query.Get("cursor"),
+		Limit:        tc.Cfg.History.PageSize,
+	}
+
+	var err error
+	if value := query.Get("from"); value != "" {
+		if request.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, err
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if request.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, err
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if request.Limit, err = strconv.Atoi(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return request, nil
+}
@@ -1,59 +1,92 @@
 package authTokenMiddleware
 
 import (
-	"context"
 	"log/slog"
 	"net/http"
 	"strings"
 
-	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	"github.com/eslupmi101/avito_merch_store/internal/utility"
 )
 
-func Authorization(secret string) func(http.Handler) http.Handler {
+// Authorization puts the Principal of a valid bearer token into the request
+// context. Requests without a token pass through anonymously; use RequireAuth
+// to reject them.
+func Authorization(signer domain.TokenSigner, sessionRepository domain.SessionRepository) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 
-			values, ok := ctx.Value(config.AuthMiddlewareValuesKey).(map[string]interface{})
-			if !ok {
-				values = make(map[string]interface{})
-			}
-			values["isAuthorized"] = false
-
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				ctx = context.WithValue(ctx, config.AuthMiddlewareValuesKey, values)
-				next.ServeHTTP(w, r.WithContext(ctx))
+				next.ServeHTTP(w, r)
 				return
 			}
 
 			const prefix = "Bearer "
 			if !strings.HasPrefix(authHeader, prefix) {
 				slog.Debug("authHeader")
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				utility.WriteError(w, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
 
-			token := strings.Split(authHeader, " ")[1]
+			token := strings.TrimPrefix(authHeader, prefix)
 
-			valid, err := utility.IsAuthorized(token, secret)
-			if err != nil || !valid {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			claims, err := utility.ParseToken(signer, token)
+			if err != nil {
+				slog.Debug("Invalid token", slog.String("error", err.Error()))
+				utility.WriteError(w, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
 
-			values["isAuthorized"] = true
-
-			userID, err := utility.ExtractIDFromToken(token, secret)
+			active, err := sessionRepository.IsActive(ctx, claims.ID)
 			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				slog.Error("Failed to check session", slog.String("error", err.Error()))
+				utility.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			if !active {
+				slog.Debug("Session revoked or expired", slog.String("sessionID", claims.ID))
+				utility.WriteError(w, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
-			values["userID"] = userID
 
-			ctx = context.WithValue(ctx, config.AuthMiddlewareValuesKey, values)
+			ctx = domain.WithPrincipal(ctx, claims.Principal())
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// RequireAuth rejects requests that Authorization left anonymous.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := domain.PrincipalFromContext(r.Context()); !ok {
+			slog.Debug("User not authorized", slog.String("path", r.URL.Path))
+			utility.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole lets through principals holding at least one of roles and
+// rejects everyone else with 403. It must run after RequireAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := domain.MustPrincipal(r.Context())
+			if !principal.HasAnyRole(roles...) {
+				slog.Info(
+					"Access denied",
+					slog.Int("userID", principal.UserID),
+					slog.String("path", r.URL.Path),
+				)
+				utility.WriteError(w, http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
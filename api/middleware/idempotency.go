@@ -0,0 +1,141 @@
+package authTokenMiddleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	idempotencyStoreTimeout  = 5 * time.Second
+)
+
+// Idempotency replays the stored response when an authenticated client
+// retries a request with the same Idempotency-Key header, so the handler runs
+// at most once per key. Requests without the header are passed through.
+// Server errors are not stored, which lets the client retry them.
+func Idempotency(repository domain.IdempotencyRepository, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				utility.WriteError(w, http.StatusBadRequest, "Invalid Idempotency-Key")
+				return
+			}
+
+			ctx := r.Context()
+			userID := domain.MustPrincipal(ctx).UserID
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				slog.Warn("Failed to read request body", slog.String("error", err.Error()))
+				utility.WriteError(w, http.StatusBadRequest, "Invalid body/json")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record, reserved, err := repository.Reserve(ctx, userID, key, fingerprint(r, body), ttl)
+			if err != nil {
+				slog.Error("Failed to reserve idempotency key", slog.String("error", err.Error()))
+				utility.WriteError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+
+			if !reserved {
+				replay(w, r, record, body)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			handled := false
+			defer func() {
+				if handled {
+					return
+				}
+				// The handler failed or panicked: forget the key so the client can retry.
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+				defer cancel()
+				if err := repository.Release(ctx, userID, key); err != nil {
+					slog.Error("Failed to release idempotency key", slog.String("error", err.Error()))
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			// The request took effect, so the key is never released from here
+			// on, even if storing the response fails.
+			handled = true
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+			defer cancel()
+			if err := repository.Complete(ctx, userID, key, recorder.status, recorder.body.Bytes()); err != nil {
+				slog.Error("Failed to store idempotent response", slog.String("error", err.Error()))
+			}
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, record *domain.IdempotencyRecord, body []byte) {
+	switch {
+	case record.Fingerprint != fingerprint(r, body):
+		utility.WriteError(w, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+	case !record.Completed():
+		utility.WriteError(w, http.StatusConflict, "Request with this Idempotency-Key is in progress")
+	default:
+		slog.Info("Replaying idempotent response", slog.Int("userID", record.UserID), slog.String("key", record.Key))
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		if len(record.ResponseBody) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(record.StatusCode)
+		_, _ = w.Write(record.ResponseBody)
+	}
+}
+
+// fingerprint identifies the request a key was first used with.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
@@ -0,0 +1,35 @@
+package authTokenMiddleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+// Recoverer recovers from panics in downstream handlers and responds with
+// a JSON 500 instead of the empty body written by chi's Recoverer.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+
+			slog.Error(
+				"Recovered from panic",
+				slog.String("panic", fmt.Sprint(rvr)),
+				slog.String("stack", string(debug.Stack())),
+			)
+			utility.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,24 @@
+package route
+
+import (
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+func NewAdmin(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	ur := repository.NewUserRepository(db)
+	sr := repository.NewSessionRepository(db)
+	lr := repository.NewLedgerRepository(db)
+	ac := &controller.Admin{
+		AdminUsecase: usecase.NewAdmin(ur, sr, lr, timeout),
+		Cfg:          cfg,
+	}
+	router.Put("/api/admin/users/{username}/roles", ac.SetUserRoles)
+	router.Post("/api/admin/users/{username}/coins/grant", ac.GrantCoins)
+	router.Post("/api/admin/users/{username}/coins/deduct", ac.DeductCoins)
+}
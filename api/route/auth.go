@@ -4,17 +4,35 @@ import (
 	"time"
 
 	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	"github.com/eslupmi101/avito_merch_store/internal/repository"
 	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
 	"github.com/go-chi/chi/v5"
 )
 
-func NewAuth(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+func NewAuth(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, signer domain.TokenSigner, router chi.Router) {
 	ur := repository.NewUserRepository(db)
+	sr := repository.NewSessionRepository(db)
+	ph := utility.NewPasswordHasher(cfg.Auth.BcryptCost, cfg.Auth.BcryptWorkers)
 	ac := &controller.Auth{
-		AuthUsecase: usecase.NewAuth(ur, timeout),
-		Cfg:         cfg,
+		AuthUsecase: usecase.NewAuth(
+			ur,
+			sr,
+			ph,
+			signer,
+			cfg.Auth.AccessTokenTTL,
+			cfg.Auth.RefreshTokenTTL,
+			cfg.Coins.StartingBalance,
+			timeout,
+		),
+		Cfg: cfg,
 	}
 	router.Post("/api/auth", ac.Authentication)
+	router.Post("/api/auth/login", ac.Login)
+	router.Post("/api/auth/register", ac.Register)
+	router.Post("/api/auth/refresh", ac.Refresh)
+	router.With(authTokenMiddleware.RequireAuth).Post("/api/auth/logout", ac.Logout)
 }
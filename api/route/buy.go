@@ -16,5 +16,7 @@ func NewBuy(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, ro
 		BuyUsecase: usecase.NewOrder(or, timeout),
 		Cfg:        cfg,
 	}
+	// GET is kept for compatibility with existing clients.
 	router.Get("/api/buy/{merchName}", bc.Buy)
+	router.With(idempotent(cfg, db)).Post("/api/buy/{merchName}", bc.Buy)
 }
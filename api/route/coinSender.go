@@ -16,5 +16,14 @@ func NewCoinSender(cfg *config.Config, timeout time.Duration, db *config.Postgre
 		CoinSenderUsecase: usecase.NewCoinSender(tr, timeout),
 		Cfg:               cfg,
 	}
-	router.Post("/api/sendCoin", scc.CoinSender)
+	router.With(idempotent(cfg, db)).Post("/api/sendCoin", scc.CoinSender)
+}
+
+func NewBulkCoinSender(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	tr := repository.NewTransactionRepository(db)
+	scc := &controller.CoinSender{
+		CoinSenderUsecase: usecase.NewCoinSender(tr, timeout),
+		Cfg:               cfg,
+	}
+	router.With(idempotent(cfg, db)).Post("/api/sendCoin/bulk", scc.Bulk)
 }
@@ -0,0 +1,14 @@
+package route
+
+import (
+	"net/http"
+
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+)
+
+// idempotent guards state-changing routes with the Idempotency-Key middleware.
+func idempotent(cfg *config.Config, db *config.PostgresDb) func(http.Handler) http.Handler {
+	return authTokenMiddleware.Idempotency(repository.NewIdempotencyRepository(db), cfg.Idempotency.KeyTTL)
+}
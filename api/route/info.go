@@ -21,6 +21,7 @@ func NewInfo(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, r
 			mr,
 			tr,
 			ur,
+			cfg.History.InfoLimit,
 			timeout,
 		),
 		Cfg: cfg,
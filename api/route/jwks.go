@@ -0,0 +1,14 @@
+package route
+
+import (
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+func NewJWKS(signer domain.TokenSigner, router chi.Router) {
+	jc := &controller.JWKS{
+		TokenSigner: signer,
+	}
+	router.Get("/.well-known/jwks.json", jc.Keys)
+}
@@ -0,0 +1,32 @@
+package route
+
+import (
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+func newMerchController(cfg *config.Config, timeout time.Duration, db *config.PostgresDb) *controller.Merch {
+	mr := repository.NewMerchRepository(db)
+	return &controller.Merch{
+		MerchUsecase: usecase.NewMerch(mr, timeout),
+		Cfg:          cfg,
+	}
+}
+
+func NewMerch(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	mc := newMerchController(cfg, timeout, db)
+	router.Get("/api/merch", mc.Catalog)
+}
+
+func NewAdminMerch(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	mc := newMerchController(cfg, timeout, db)
+	router.Get("/api/admin/merch", mc.List)
+	router.Post("/api/admin/merch", mc.Create)
+	router.Put("/api/admin/merch/{id}", mc.Update)
+	router.Delete("/api/admin/merch/{id}", mc.Archive)
+}
@@ -0,0 +1,39 @@
+package route
+
+import (
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+func newOrdersController(cfg *config.Config, timeout time.Duration, db *config.PostgresDb) *controller.Orders {
+	or := repository.NewOrderRepository(db)
+	return &controller.Orders{
+		OrderUsecase: usecase.NewOrders(or, timeout),
+		Cfg:          cfg,
+	}
+}
+
+func NewOrders(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	oc := newOrdersController(cfg, timeout, db)
+	router.Get("/api/orders", oc.List)
+	router.With(idempotent(cfg, db)).Post("/api/orders", oc.PlaceOrder)
+	router.Post("/api/orders/{id}/cancel", oc.Cancel)
+}
+
+func NewStaffOrders(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	oc := newOrdersController(cfg, timeout, db)
+	router.Get("/api/staff/orders", oc.Pending)
+	router.Get("/api/staff/orders/{id}/events", oc.Events)
+	router.Post("/api/staff/orders/{id}/pick", oc.Pick)
+	router.Post("/api/staff/orders/{id}/deliver", oc.Deliver)
+}
+
+func NewAdminOrders(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	oc := newOrdersController(cfg, timeout, db)
+	router.Post("/api/admin/orders/{id}/refund", oc.Refund)
+}
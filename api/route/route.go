@@ -3,15 +3,59 @@ package route
 import (
 	"time"
 
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	"github.com/go-chi/chi/v5"
 )
 
-func Setup(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, r *chi.Mux) {
+func Setup(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, signer domain.TokenSigner, r *chi.Mux) {
+	r.NotFound(controller.NotFound)
+	r.MethodNotAllowed(controller.MethodNotAllowed)
+
+	// Public routes
 	r.Group(func(r chi.Router) {
-		NewAuth(cfg, timeout, db, r)
+		NewAuth(cfg, timeout, db, signer, r)
+		NewJWKS(signer, r)
+		NewMerch(cfg, timeout, db, r)
+	})
+
+	// Routes that require an authenticated principal
+	r.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireAuth)
+
 		NewBuy(cfg, timeout, db, r)
+		NewOrders(cfg, timeout, db, r)
 		NewCoinSender(cfg, timeout, db, r)
 		NewInfo(cfg, timeout, db, r)
+		NewTransactions(cfg, timeout, db, r)
+		NewScheduledTransfers(cfg, timeout, db, r)
+	})
+
+	// Routes for team leads
+	r.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireAuth)
+		r.Use(authTokenMiddleware.RequireRole(domain.RoleTeamLead, domain.RoleAdmin))
+
+		NewBulkCoinSender(cfg, timeout, db, r)
+	})
+
+	// Routes for store staff
+	r.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireAuth)
+		r.Use(authTokenMiddleware.RequireRole(domain.RoleStoreManager, domain.RoleAdmin))
+
+		NewStaffOrders(cfg, timeout, db, r)
+	})
+
+	// Routes for administrators only
+	r.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireAuth)
+		r.Use(authTokenMiddleware.RequireRole(domain.RoleAdmin))
+
+		NewAdmin(cfg, timeout, db, r)
+		NewAdminMerch(cfg, timeout, db, r)
+		NewAdminOrders(cfg, timeout, db, r)
 	})
 }
@@ -0,0 +1,23 @@
+package route
+
+import (
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+func NewScheduledTransfers(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	str := repository.NewScheduledTransferRepository(db)
+	stc := &controller.ScheduledTransfers{
+		ScheduledTransferUsecase: usecase.NewScheduledTransfers(str, cfg.Scheduler.BatchSize, timeout),
+		Cfg:                      cfg,
+	}
+	router.With(idempotent(cfg, db)).Post("/api/scheduledTransfers", stc.Schedule)
+	router.Get("/api/scheduledTransfers", stc.List)
+	router.Post("/api/scheduledTransfers/{id}/cancel", stc.Cancel)
+	router.Get("/api/scheduledTransfers/{id}/runs", stc.Runs)
+}
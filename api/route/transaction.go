@@ -0,0 +1,21 @@
+package route
+
+import (
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+func NewTransactions(cfg *config.Config, timeout time.Duration, db *config.PostgresDb, router chi.Router) {
+	tr := repository.NewTransactionRepository(db)
+	tc := &controller.Transactions{
+		TransactionUsecase: usecase.NewTransactions(tr, timeout),
+		Cfg:                cfg,
+	}
+	router.Get("/api/transactions", tc.List)
+	router.Post("/api/transactions/{id}/reaction", tc.React)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+)
+
+// allowanceTimeout bounds a single run, which updates every active user.
+const allowanceTimeout = 5 * time.Minute
+
+// runAllowance credits the monthly allowance at start-up and then checks
+// every CheckInterval whether a new month has begun. Several instances may
+// run it: the period is claimed in the database, so each month is paid once.
+func runAllowance(ctx context.Context, db *config.PostgresDb, cfg config.Allowance) {
+	allowance := usecase.NewAllowance(repository.NewAllowanceRepository(db), cfg.Amount, cfg.ActiveWithin, allowanceTimeout)
+
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		run, err := allowance.CreditDue(ctx, time.Now())
+		if err != nil {
+			slog.Error("Failed to credit allowance", slog.String("error", err.Error()))
+		} else if run != nil {
+			slog.Info("Allowance credited", slog.Time("period", run.Period), slog.Int("users", run.CreditedUsers))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+)
+
+// runIdempotencyCleanup deletes idempotency keys older than KeyTTL every
+// CleanupInterval. Reserve only discards the expired key it is asked about,
+// so keys that are never reused would otherwise stay forever.
+func runIdempotencyCleanup(ctx context.Context, db *config.PostgresDb, cfg config.Idempotency, timeout time.Duration) {
+	idempotencyRepository := repository.NewIdempotencyRepository(db)
+
+	ticker := time.NewTicker(cfg.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		cleanupCtx, cancel := context.WithTimeout(ctx, timeout)
+		deleted, err := idempotencyRepository.DeleteExpired(cleanupCtx, time.Now().Add(-cfg.KeyTTL))
+		cancel()
+		if err != nil {
+			slog.Error("Failed to delete expired idempotency keys", slog.String("error", err.Error()))
+		} else if deleted > 0 {
+			slog.Info("Expired idempotency keys deleted", slog.Int64("count", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+)
+
+const ledgerUsage = "usage: merch_store ledger reconcile"
+
+// runLedger implements the `ledger reconcile` subcommand. It exits with
+// status 1 when a balance drifted from the ledger.
+func runLedger(ctx context.Context, db *config.PostgresDb, args []string) {
+	if len(args) != 1 || args[0] != "reconcile" {
+		fmt.Fprintln(os.Stderr, ledgerUsage)
+		os.Exit(2)
+	}
+
+	report, err := repository.NewLedgerRepository(db).Reconcile(ctx)
+	if err != nil {
+		log.Fatalf("Error reconciling ledger: %v", err)
+	}
+
+	for _, drift := range report.Drifts {
+		fmt.Printf("drift user %d (%s): balance %d, ledger %d, difference %d\n",
+			drift.UserID, drift.Username, drift.Balance, drift.LedgerBalance, drift.Balance-drift.LedgerBalance)
+	}
+	for _, entryID := range report.UnbalancedEntries {
+		fmt.Printf("unbalanced entry %d\n", entryID)
+	}
+
+	fmt.Printf("checked %d users: %d drifted, %d unbalanced entries\n",
+		report.CheckedUsers, len(report.Drifts), len(report.UnbalancedEntries))
+	if !report.Consistent() {
+		os.Exit(1)
+	}
+}
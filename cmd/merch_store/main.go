@@ -11,6 +11,8 @@ import (
 	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
 	"github.com/eslupmi101/avito_merch_store/api/route"
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -40,25 +42,48 @@ func main() {
 	db := config.NewPostgresDb(ctx, connStr)
 	defer db.Close()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(context.Background(), db, os.Args[2:])
+		case "ledger":
+			runLedger(context.Background(), db, os.Args[2:])
+		default:
+			log.Fatalf("Unknown command: %s", os.Args[1])
+		}
+		return
+	}
+
+	signer, err := utility.LoadTokenSigner(cfg.Auth.SigningKeys, cfg.SecretKey)
+	if err != nil {
+		log.Fatalf("Error loading token signing keys: %v", err)
+	}
+
 	router := chi.NewRouter()
 
 	cors := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Origin", "X-Requested-With"},
-		ExposedHeaders:   []string{"Link", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "Origin", "X-Requested-With"},
+		ExposedHeaders:   []string{"Link", "Content-Type", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
 
 	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
+	router.Use(authTokenMiddleware.Recoverer)
 	router.Use(middleware.URLFormat)
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(authTokenMiddleware.Authorization(signer, repository.NewSessionRepository(db)))
 
 	router.Use(cors.Handler)
 
-	route.Setup(&cfg, cfg.HTTPServer.Timeout, db, router)
+	route.Setup(&cfg, cfg.HTTPServer.Timeout, db, signer, router)
+
+	if cfg.Coins.Allowance.Amount > 0 {
+		go runAllowance(context.Background(), db, cfg.Coins.Allowance)
+	}
+	go runScheduler(context.Background(), db, cfg.Scheduler, cfg.HTTPServer.Timeout)
+	go runIdempotencyCleanup(context.Background(), db, cfg.Idempotency, cfg.HTTPServer.Timeout)
 
 	http.ListenAndServe(cfg.HTTPServer.Address, router)
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/migration"
+)
+
+const migrateUsage = "usage: merch_store migrate up|down|status"
+
+// runMigrate implements the `migrate up|down|status` subcommand.
+func runMigrate(ctx context.Context, db *config.PostgresDb, args []string) {
+	if len(args) != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			log.Fatalf("Error reverting migration: %v", err)
+		}
+		if reverted == nil {
+			fmt.Println("no migrations to revert")
+			return
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
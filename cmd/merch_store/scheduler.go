@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+)
+
+// runScheduler executes due scheduled transfers every Interval. Each
+// transfer is claimed with SKIP LOCKED, so several instances can run it.
+func runScheduler(ctx context.Context, db *config.PostgresDb, cfg config.Scheduler, timeout time.Duration) {
+	scheduler := usecase.NewScheduledTransfers(repository.NewScheduledTransferRepository(db), cfg.BatchSize, timeout)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		executed, err := scheduler.RunDue(ctx, time.Now())
+		if err != nil {
+			slog.Error("Failed to run scheduled transfers", slog.String("error", err.Error()))
+		} else if executed > 0 {
+			slog.Info("Scheduled transfers executed", slog.Int("count", executed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -11,13 +11,19 @@ import (
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Config struct {
-	SecretKey  string
-	Env        string `yaml:"env" env-default:"local"`
-	HTTPServer `yaml:"http_server"`
-	Database   Database `yaml:"database"`
+	SecretKey   string
+	Env         string `yaml:"env" env-default:"local"`
+	HTTPServer  `yaml:"http_server"`
+	Database    Database    `yaml:"database"`
+	Auth        Auth        `yaml:"auth"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	History     History     `yaml:"history"`
+	Coins       Coins       `yaml:"coins"`
+	Scheduler   Scheduler   `yaml:"scheduler"`
 }
 
 type HTTPServer struct {
@@ -26,6 +32,65 @@ type HTTPServer struct {
 	Idle_timeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
+type Auth struct {
+	Mode          string `yaml:"mode" env-default:"auto_register"`
+	BcryptCost    int    `yaml:"bcrypt_cost" env:"BCRYPT_COST" env-default:"10"`
+	BcryptWorkers int    `yaml:"bcrypt_workers" env:"BCRYPT_WORKERS" env-default:"0"`
+
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+
+	// SigningKeys replace the HS256 SECRET_KEY when set. Exactly one key is
+	// active; the others only verify tokens until their verify_until.
+	SigningKeys []SigningKey `yaml:"signing_keys"`
+}
+
+type Idempotency struct {
+	// KeyTTL is how long a response is replayed for a repeated Idempotency-Key.
+	KeyTTL time.Duration `yaml:"key_ttl" env-default:"24h"`
+	// CleanupInterval is how often keys older than KeyTTL are deleted.
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+}
+
+type History struct {
+	// InfoLimit caps the transfers embedded into /api/info.
+	InfoLimit int `yaml:"info_limit" env-default:"20"`
+	// PageSize and MaxPageSize apply to /api/transactions.
+	PageSize    int `yaml:"page_size" env-default:"20"`
+	MaxPageSize int `yaml:"max_page_size" env-default:"100"`
+}
+
+type Coins struct {
+	// StartingBalance is granted to every new account.
+	StartingBalance int       `yaml:"starting_balance" env:"STARTING_BALANCE" env-default:"10000000"`
+	Allowance       Allowance `yaml:"allowance"`
+}
+
+type Allowance struct {
+	// Amount is credited once a month to every active user. Zero disables the job.
+	Amount int `yaml:"amount" env-default:"0"`
+	// ActiveWithin is how recently a user must have logged in to be active.
+	ActiveWithin time.Duration `yaml:"active_within" env-default:"720h"`
+	// CheckInterval is how often the job checks whether the month was paid.
+	CheckInterval time.Duration `yaml:"check_interval" env-default:"1h"`
+}
+
+// Scheduler executes scheduled transfers that are due.
+type Scheduler struct {
+	// Interval is how often due transfers are looked up.
+	Interval time.Duration `yaml:"interval" env-default:"1m"`
+	// BatchSize caps the transfers executed per interval.
+	BatchSize int `yaml:"batch_size" env-default:"100"`
+}
+
+type SigningKey struct {
+	ID             string    `yaml:"kid"`
+	Algorithm      string    `yaml:"algorithm"`
+	PrivateKeyPath string    `yaml:"private_key_path"`
+	Active         bool      `yaml:"active"`
+	VerifyUntil    time.Time `yaml:"verify_until"`
+}
+
 type Database struct {
 	Host     string `yaml:"host" env-default:"localhost"`
 	Port     int    `yaml:"port" env-default:"5432"`
@@ -65,13 +130,6 @@ func NewConfig() Config {
 		log.Println("No .env file found")
 	}
 
-	secretKey := os.Getenv("SECRET_KEY")
-	if secretKey == "" {
-		log.Fatalf("SECRET_KEY is not set")
-	}
-
-	cfg.SecretKey = secretKey
-
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
 		log.Fatalf("CONFIG_PATH is not set")
@@ -93,6 +151,40 @@ func NewConfig() Config {
 		log.Fatalf("Cannot read config file: %s", err)
 	}
 
+	cfg.SecretKey = os.Getenv("SECRET_KEY")
+	if cfg.SecretKey == "" && len(cfg.Auth.SigningKeys) == 0 {
+		log.Fatalf("SECRET_KEY is not set")
+	}
+
+	if cfg.Auth.Mode != AuthModeAutoRegister && cfg.Auth.Mode != AuthModeLogin {
+		log.Fatalf("Invalid auth mode: %s", cfg.Auth.Mode)
+	}
+
+	if cfg.Auth.BcryptCost < bcrypt.MinCost || cfg.Auth.BcryptCost > bcrypt.MaxCost {
+		log.Fatalf("Invalid bcrypt cost: %d", cfg.Auth.BcryptCost)
+	}
+
+	if cfg.History.InfoLimit <= 0 || cfg.History.PageSize <= 0 || cfg.History.PageSize > cfg.History.MaxPageSize {
+		log.Fatalf("Invalid history limits: %+v", cfg.History)
+	}
+
+	if cfg.Coins.StartingBalance < 0 || cfg.Coins.StartingBalance > 100000000 {
+		log.Fatalf("Invalid starting balance: %d", cfg.Coins.StartingBalance)
+	}
+
+	if cfg.Coins.Allowance.Amount < 0 || cfg.Coins.Allowance.Amount > 100000000 ||
+		cfg.Coins.Allowance.ActiveWithin <= 0 || cfg.Coins.Allowance.CheckInterval <= 0 {
+		log.Fatalf("Invalid allowance settings: %+v", cfg.Coins.Allowance)
+	}
+
+	if cfg.Idempotency.KeyTTL <= 0 || cfg.Idempotency.CleanupInterval <= 0 {
+		log.Fatalf("Invalid idempotency settings: %+v", cfg.Idempotency)
+	}
+
+	if cfg.Scheduler.Interval <= 0 || cfg.Scheduler.BatchSize <= 0 {
+		log.Fatalf("Invalid scheduler settings: %+v", cfg.Scheduler)
+	}
+
 	cfgInstance = &cfg
 
 	return cfg
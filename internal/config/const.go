@@ -1,5 +1,9 @@
 package config
 
-type contextKey string
-
-const AuthMiddlewareValuesKey contextKey = "middlewareValues"
+// Authentication modes of /api/auth.
+const (
+	// AuthModeAutoRegister creates an account for unknown usernames.
+	AuthModeAutoRegister = "auto_register"
+	// AuthModeLogin only authenticates existing users.
+	AuthModeLogin = "login"
+)
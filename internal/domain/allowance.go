@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AllowanceRun is the monthly allowance credited for Period, the first day
+// of a month in UTC.
+type AllowanceRun struct {
+	Period        time.Time
+	Amount        int
+	CreditedUsers int
+	CreatedAt     time.Time
+}
+
+// AllowancePeriod returns the month t belongs to.
+func AllowancePeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+type AllowanceRepository interface {
+	// Credit pays amount to every user who logged in since activeSince,
+	// once per period. It reports false when the period was already paid.
+	Credit(ctx context.Context, period time.Time, amount int, activeSince time.Time) (*AllowanceRun, bool, error)
+}
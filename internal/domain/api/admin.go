@@ -0,0 +1,69 @@
+package domainAPI
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+)
+
+const MaxAdjustmentReasonLength = 255
+
+type SetRolesRequest struct {
+	Roles []string `json:"roles" form:"roles" binding:"required"`
+}
+
+type UserRolesResponse struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
+
+// CoinAdjustmentRequest grants or deducts Amount coins, depending on the endpoint.
+type CoinAdjustmentRequest struct {
+	Amount int    `json:"amount" form:"amount" binding:"required"`
+	Reason string `json:"reason" form:"reason" binding:"required"`
+}
+
+type BalanceResponse struct {
+	Username string `json:"username"`
+	Coins    int    `json:"coins"`
+}
+
+type AdminUsecase interface {
+	SetUserRoles(ctx context.Context, username string, roles []string) (*UserRolesResponse, error)
+	GrantCoins(ctx context.Context, adminID int, username string, request *CoinAdjustmentRequest) (*BalanceResponse, error)
+	DeductCoins(ctx context.Context, adminID int, username string, request *CoinAdjustmentRequest) (*BalanceResponse, error)
+}
+
+func (sr *SetRolesRequest) ValidateRoles() error {
+	for _, role := range sr.Roles {
+		if !domain.IsValidRole(role) {
+			return domain.ErrInvalidRole
+		}
+	}
+	return nil
+}
+
+func (cr *CoinAdjustmentRequest) ValidateAmount() error {
+	if cr.Amount <= 0 || cr.Amount > domain.MaxBalance {
+		return errors.New("invalid amount")
+	}
+	return nil
+}
+
+// ValidateReason trims the reason, which is stored in the ledger as is.
+func (cr *CoinAdjustmentRequest) ValidateReason() error {
+	cr.Reason = strings.TrimSpace(cr.Reason)
+	if cr.Reason == "" || utf8.RuneCountInString(cr.Reason) > MaxAdjustmentReasonLength {
+		return errors.New("invalid reason")
+	}
+	for _, r := range cr.Reason {
+		if !unicode.IsPrint(r) {
+			return errors.New("invalid reason")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package domainAPI
+
+import (
+	"context"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+)
+
+type AllowanceUsecase interface {
+	// CreditDue pays the allowance for the month of now unless it was paid
+	// already, in which case it returns nil.
+	CreditDue(ctx context.Context, now time.Time) (*domain.AllowanceRun, error)
+}
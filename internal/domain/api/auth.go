@@ -14,12 +14,26 @@ type AuthRequest struct {
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" form:"refreshToken" binding:"required"`
+}
+
+type LogoutRequest struct {
+	AllSessions bool `json:"allSessions" form:"allSessions"`
 }
 
 type AuthUsecase interface {
 	GetOrCreateByUsernamePassword(ctx context.Context, username, password string) (*domain.User, error)
-	CreateToken(userID int, secretKey string) (string, error)
+	Login(ctx context.Context, username, password string) (*domain.User, error)
+	Register(ctx context.Context, username, password string) (*domain.User, error)
+	CreateTokens(ctx context.Context, user *domain.User) (*AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*AuthResponse, error)
+	Logout(ctx context.Context, userID int, sessionID string, allSessions bool) error
 }
 
 func (ar *AuthRequest) ValidateUsername() error {
@@ -3,18 +3,26 @@ package domainAPI
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"unicode/utf8"
 
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	"github.com/eslupmi101/avito_merch_store/internal/utility"
 )
 
 type CoinSenderRequest struct {
 	ToUser string `json:"toUser" form:"toUser" binding:"required"`
 	Amount int    `json:"amount" form:"amount" binding:"required"`
+	// Message is an optional memo shown to both sides in the coin history.
+	Message string `json:"message,omitempty" form:"message"`
 }
 
 type CoinSenderUsecase interface {
-	SendCoinToUser(ctx context.Context, userID int, ToUser string, amount int) error
+	SendCoinToUser(ctx context.Context, userID int, ToUser string, amount int, message string) error
+	// SendCoinsBulk returns the per-recipient results also when the batch
+	// fails.
+	SendCoinsBulk(ctx context.Context, userID int, request *BulkSendRequest) (*BulkSendResponse, error)
 }
 
 func (cs *CoinSenderRequest) ValidateToUser() error {
@@ -33,3 +41,88 @@ func (cs *CoinSenderRequest) ValidateAmount() error {
 
 	return nil
 }
+
+// ValidateMessage sanitizes the memo before checking its length.
+func (cs *CoinSenderRequest) ValidateMessage() error {
+	cs.Message = utility.SanitizeMessage(cs.Message)
+	if utf8.RuneCountInString(cs.Message) > domain.MaxTransferMessageLength {
+		slog.Info("Message is too long", slog.Int("length", utf8.RuneCountInString(cs.Message)))
+		return errors.New("message is too long")
+	}
+
+	return nil
+}
+
+// Statuses of an entry in the bulk transfer response. A batch is sent as a
+// whole, so when any entry fails the others are not_sent.
+const (
+	BulkTransferSent    = "sent"
+	BulkTransferFailed  = "failed"
+	BulkTransferNotSent = "not_sent"
+)
+
+// MaxBulkTransfers caps the recipients of a single bulk transfer.
+const MaxBulkTransfers = 100
+
+type BulkSendRequest struct {
+	Transfers []CoinSenderRequest `json:"transfers" form:"transfers" binding:"required"`
+	// Message is used for every transfer that has no message of its own.
+	Message string `json:"message,omitempty" form:"message"`
+}
+
+type BulkTransferResult struct {
+	ToUser        string `json:"toUser"`
+	Amount        int    `json:"amount"`
+	Status        string `json:"status"`
+	TransactionID int    `json:"transactionId,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BulkSendResponse is returned both on success and, with Errors set, when
+// the batch was rejected or failed. Coins is the balance after a
+// successful batch.
+type BulkSendResponse struct {
+	Errors  string               `json:"errors,omitempty"`
+	Coins   *int                 `json:"coins,omitempty"`
+	Total   int                  `json:"total"`
+	Results []BulkTransferResult `json:"results"`
+}
+
+// Validate checks every entry like /api/sendCoin does and rejects batches
+// that name a recipient twice.
+func (bs *BulkSendRequest) Validate() error {
+	if len(bs.Transfers) == 0 || len(bs.Transfers) > MaxBulkTransfers {
+		return errors.New("invalid number of transfers")
+	}
+
+	bs.Message = utility.SanitizeMessage(bs.Message)
+	total := 0
+	seen := map[string]bool{}
+	for i := range bs.Transfers {
+		transfer := &bs.Transfers[i]
+		if transfer.Message == "" {
+			transfer.Message = bs.Message
+		}
+
+		if err := transfer.ValidateToUser(); err != nil {
+			return fmt.Errorf("transfers[%d]: %w", i, err)
+		}
+		if err := transfer.ValidateAmount(); err != nil {
+			return fmt.Errorf("transfers[%d]: %w", i, err)
+		}
+		if err := transfer.ValidateMessage(); err != nil {
+			return fmt.Errorf("transfers[%d]: %w", i, err)
+		}
+		if seen[transfer.ToUser] {
+			return fmt.Errorf("transfers[%d]: duplicate toUser %s", i, transfer.ToUser)
+		}
+		seen[transfer.ToUser] = true
+
+		total += transfer.Amount
+		if total > domain.MaxBalance {
+			return errors.New("total amount exceeds the balance limit")
+		}
+	}
+
+	return nil
+}
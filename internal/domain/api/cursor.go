@@ -0,0 +1,26 @@
+package domainAPI
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+// EncodeCursor makes an opaque cursor pointing past the row with the given id.
+func EncodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+
+	return id, nil
+}
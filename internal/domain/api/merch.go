@@ -0,0 +1,83 @@
+package domainAPI
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+// MaxMerchPrice matches the price CHECK constraint of the merch table.
+const MaxMerchPrice = 100000000
+
+type MerchRequest struct {
+	Name     string `json:"name" form:"name" binding:"required"`
+	Price    int    `json:"price" form:"price" binding:"required"`
+	Archived bool   `json:"archived" form:"archived"`
+	// Stock limits the number of items left. On create, omitted or null
+	// means unlimited; on update it keeps the current stock.
+	Stock *int `json:"stock" form:"stock"`
+	// StockDelta is added to the current stock on update.
+	StockDelta int `json:"stockDelta" form:"stockDelta"`
+	// Unlimited removes the stock limit on update.
+	Unlimited bool `json:"unlimited" form:"unlimited"`
+}
+
+type CatalogItem struct {
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+	Stock *int   `json:"stock"`
+}
+
+type CatalogResponse struct {
+	Items []CatalogItem `json:"items"`
+}
+
+type MerchUsecase interface {
+	GetCatalog(ctx context.Context) (*CatalogResponse, error)
+	List(ctx context.Context) ([]domain.Merch, error)
+	Create(ctx context.Context, request *MerchRequest) (*domain.Merch, error)
+	Update(ctx context.Context, id int, request *MerchRequest) (*domain.Merch, error)
+	Archive(ctx context.Context, id int) (*domain.Merch, error)
+}
+
+func (mr *MerchRequest) ValidateName() error {
+	if err := utility.ValidateMerchName(mr.Name); err != nil {
+		return errors.New("invalid merch name format")
+	}
+	return nil
+}
+
+func (mr *MerchRequest) ValidatePrice() error {
+	if mr.Price <= 0 || mr.Price > MaxMerchPrice {
+		return errors.New("invalid price")
+	}
+	return nil
+}
+
+// ValidateStock allows one way of changing the stock per request.
+func (mr *MerchRequest) ValidateStock() error {
+	if mr.Stock != nil && *mr.Stock < 0 {
+		return errors.New("invalid stock")
+	}
+
+	changes := 0
+	for _, set := range []bool{mr.Stock != nil, mr.StockDelta != 0, mr.Unlimited} {
+		if set {
+			changes++
+		}
+	}
+	if changes > 1 {
+		return errors.New("stock, stockDelta and unlimited are mutually exclusive")
+	}
+	return nil
+}
+
+// ValidateCreateStock rejects the fields that only change an existing stock.
+func (mr *MerchRequest) ValidateCreateStock() error {
+	if mr.StockDelta != 0 || mr.Unlimited {
+		return errors.New("stockDelta and unlimited only apply to updates")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package domainAPI
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+const (
+	MaxOrderLines             = 50
+	MaxOrderQuantity          = 1000
+	MaxDeliveryLocationLength = 255
+)
+
+type OrderRequest struct {
+	Items []OrderLine `json:"items" form:"items" binding:"required"`
+}
+
+type OrderLine struct {
+	Merch    string `json:"merch" form:"merch" binding:"required"`
+	Quantity int    `json:"quantity" form:"quantity" binding:"required"`
+}
+
+type OrderResponse struct {
+	OrderID   int       `json:"orderId"`
+	TotalCost int       `json:"totalCost"`
+	Coins     int       `json:"coins"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type DeliverRequest struct {
+	Location string `json:"location" form:"location" binding:"required"`
+}
+
+type OrderDetails struct {
+	OrderID          int                `json:"orderId"`
+	Owner            string             `json:"owner,omitempty"`
+	Status           string             `json:"status"`
+	TotalCost        int                `json:"totalCost"`
+	DeliveryLocation string             `json:"deliveryLocation,omitempty"`
+	CreatedAt        time.Time          `json:"createdAt"`
+	Items            []OrderItemDetails `json:"items"`
+}
+
+type OrderItemDetails struct {
+	Merch    string `json:"merch"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+type OrdersResponse struct {
+	Orders     []OrderDetails `json:"orders"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// PendingOrdersRequest holds the query parameters of /api/staff/orders.
+type PendingOrdersRequest struct {
+	Cursor string
+	Limit  int
+}
+
+type OrderEvent struct {
+	Status    string    `json:"status"`
+	Actor     string    `json:"actor,omitempty"`
+	Location  string    `json:"location,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type OrderEventsResponse struct {
+	Events []OrderEvent `json:"events"`
+}
+
+type OrderUsecase interface {
+	PlaceOrder(ctx context.Context, userID int, request *OrderRequest) (*OrderResponse, error)
+	ListOrders(ctx context.Context, userID int) (*OrdersResponse, error)
+	// CancelOrder cancels an undelivered order of the user and refunds it.
+	CancelOrder(ctx context.Context, userID, orderID int) (*OrderDetails, error)
+	ListPendingOrders(ctx context.Context, request *PendingOrdersRequest) (*OrdersResponse, error)
+	PickOrder(ctx context.Context, staffID, orderID int) (*OrderDetails, error)
+	DeliverOrder(ctx context.Context, staffID, orderID int, request *DeliverRequest) (*OrderDetails, error)
+	RefundOrder(ctx context.Context, adminID, orderID int) (*OrderDetails, error)
+	GetOrderEvents(ctx context.Context, orderID int) (*OrderEventsResponse, error)
+}
+
+func (or *OrderRequest) Validate() error {
+	if len(or.Items) == 0 || len(or.Items) > MaxOrderLines {
+		return errors.New("invalid number of order items")
+	}
+
+	for _, line := range or.Items {
+		if err := utility.ValidateMerchName(line.Merch); err != nil {
+			return errors.New("invalid merch name format")
+		}
+		if line.Quantity <= 0 || line.Quantity > MaxOrderQuantity {
+			return errors.New("invalid quantity")
+		}
+	}
+
+	return nil
+}
+
+// Validate trims the location and checks that it is a single printable line.
+func (dr *DeliverRequest) Validate() error {
+	dr.Location = strings.TrimSpace(dr.Location)
+	if dr.Location == "" || utf8.RuneCountInString(dr.Location) > MaxDeliveryLocationLength {
+		return errors.New("invalid location length")
+	}
+
+	for _, r := range dr.Location {
+		if !unicode.IsPrint(r) {
+			return errors.New("location contains non-printable characters")
+		}
+	}
+
+	return nil
+}
+
+func (pr *PendingOrdersRequest) Validate(maxLimit int) error {
+	if pr.Limit <= 0 || pr.Limit > maxLimit {
+		return errors.New("invalid limit")
+	}
+
+	if pr.Cursor != "" {
+		if _, err := DecodeCursor(pr.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
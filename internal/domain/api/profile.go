@@ -2,12 +2,14 @@ package domainAPI
 
 import (
 	"context"
+	"time"
 )
 
 type ProfileResponse struct {
-	Coins       int             `json:"coins"`
-	Inventory   []InventoryItem `json:"inventory"`
-	CoinHistory CoinHistory     `json:"coinHistory"`
+	Coins        int             `json:"coins"`
+	RegisteredAt time.Time       `json:"registeredAt"`
+	Inventory    []InventoryItem `json:"inventory"`
+	CoinHistory  CoinHistory     `json:"coinHistory"`
 }
 
 type InventoryItem struct {
@@ -21,9 +23,12 @@ type CoinHistory struct {
 }
 
 type Transaction struct {
-	FromUser string `json:"fromUser,omitempty"`
-	ToUser   string `json:"toUser,omitempty"`
-	Amount   int    `json:"amount"`
+	FromUser  string    `json:"fromUser,omitempty"`
+	ToUser    string    `json:"toUser,omitempty"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	Reaction  string    `json:"reaction,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 type ProfileUsecase interface {
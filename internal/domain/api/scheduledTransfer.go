@@ -0,0 +1,89 @@
+package domainAPI
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+// MaxScheduleAhead limits how far in the future the first run can be scheduled.
+const MaxScheduleAhead = 366 * 24 * time.Hour
+
+type ScheduledTransferRequest struct {
+	ToUser  string    `json:"toUser" form:"toUser" binding:"required"`
+	Amount  int       `json:"amount" form:"amount" binding:"required"`
+	Message string    `json:"message,omitempty" form:"message"`
+	RunAt   time.Time `json:"runAt" form:"runAt" binding:"required"`
+	// Repeat is once, weekly or monthly; empty means once.
+	Repeat string `json:"repeat,omitempty" form:"repeat"`
+}
+
+type ScheduledTransferDetails struct {
+	ID        int       `json:"id"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	Repeat    string    `json:"repeat"`
+	Status    string    `json:"status"`
+	NextRunAt time.Time `json:"nextRunAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ScheduledTransfersResponse struct {
+	ScheduledTransfers []ScheduledTransferDetails `json:"scheduledTransfers"`
+}
+
+type ScheduledTransferRun struct {
+	ScheduledAt   time.Time `json:"scheduledAt"`
+	Status        string    `json:"status"`
+	TransactionID int       `json:"transactionId,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type ScheduledTransferRunsResponse struct {
+	Runs []ScheduledTransferRun `json:"runs"`
+}
+
+type ScheduledTransferUsecase interface {
+	Schedule(ctx context.Context, userID int, request *ScheduledTransferRequest) (*ScheduledTransferDetails, error)
+	List(ctx context.Context, userID int) (*ScheduledTransfersResponse, error)
+	Cancel(ctx context.Context, userID, id int) (*ScheduledTransferDetails, error)
+	ListRuns(ctx context.Context, userID, id int) (*ScheduledTransferRunsResponse, error)
+	// RunDue executes every transfer due at now and returns how many ran.
+	RunDue(ctx context.Context, now time.Time) (int, error)
+}
+
+// Validate sanitizes the message like /api/sendCoin does and requires the
+// first run to be in the future.
+func (sr *ScheduledTransferRequest) Validate(now time.Time) error {
+	if err := utility.ValidateUsername(sr.ToUser); err != nil {
+		return errors.New("invalid toUser format")
+	}
+
+	if sr.Amount <= 0 || sr.Amount > domain.MaxBalance {
+		return errors.New("invalid amount")
+	}
+
+	sr.Message = utility.SanitizeMessage(sr.Message)
+	if utf8.RuneCountInString(sr.Message) > domain.MaxTransferMessageLength {
+		return errors.New("message is too long")
+	}
+
+	if sr.Repeat == "" {
+		sr.Repeat = domain.ScheduleRepeatOnce
+	}
+	if !domain.IsValidScheduleRepeat(sr.Repeat) {
+		return errors.New("invalid repeat")
+	}
+
+	if !sr.RunAt.After(now) || sr.RunAt.After(now.Add(MaxScheduleAhead)) {
+		return errors.New("invalid runAt")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package domainAPI
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+)
+
+// TransactionsRequest holds the query parameters of /api/transactions.
+type TransactionsRequest struct {
+	Direction    string
+	Counterparty string
+	From         time.Time
+	To           time.Time
+	Cursor       string
+	Limit        int
+}
+
+type TransactionEntry struct {
+	ID           int       `json:"id"`
+	Direction    string    `json:"direction"`
+	Counterparty string    `json:"counterparty"`
+	Amount       int       `json:"amount"`
+	Message      string    `json:"message,omitempty"`
+	Reaction     string    `json:"reaction,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type ReactionRequest struct {
+	// Reaction is one of thanks, heart, clap and fire; empty removes it.
+	Reaction string `json:"reaction" form:"reaction"`
+}
+
+type TransactionsResponse struct {
+	Transactions []TransactionEntry `json:"transactions"`
+	// NextCursor is passed as cursor to fetch the next page. It is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type TransactionUsecase interface {
+	ListTransactions(ctx context.Context, userID int, request *TransactionsRequest) (*TransactionsResponse, error)
+	React(ctx context.Context, userID, transactionID int, reaction string) error
+}
+
+func (rr *ReactionRequest) Validate() error {
+	if rr.Reaction != "" && !domain.IsValidReaction(rr.Reaction) {
+		return errors.New("invalid reaction")
+	}
+	return nil
+}
+
+func (tr *TransactionsRequest) Validate(maxLimit int) error {
+	switch tr.Direction {
+	case "", domain.TransactionDirectionSent, domain.TransactionDirectionReceived:
+	default:
+		return errors.New("invalid direction")
+	}
+
+	if tr.Counterparty != "" {
+		if err := utility.ValidateUsername(tr.Counterparty); err != nil {
+			return errors.New("invalid counterparty")
+		}
+	}
+
+	if !tr.From.IsZero() && !tr.To.IsZero() && !tr.From.Before(tr.To) {
+		return errors.New("invalid date range")
+	}
+
+	if tr.Limit <= 0 || tr.Limit > maxLimit {
+		return errors.New("invalid limit")
+	}
+
+	if tr.Cursor != "" {
+		if _, err := DecodeCursor(tr.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
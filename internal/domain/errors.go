@@ -0,0 +1,25 @@
+package domain
+
+import "errors"
+
+var (
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrMerchNotFound       = errors.New("merch does not exist")
+	ErrRecipientNotFound   = errors.New("toUser does not exist")
+	ErrSelfTransfer        = errors.New("cannot send coins to yourself")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrMerchAlreadyExists  = errors.New("merch already exists")
+	ErrOutOfStock          = errors.New("merch is out of stock")
+	ErrStockChange         = errors.New("stock change does not apply to the current stock")
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderTransition     = errors.New("order status does not allow this operation")
+	ErrBalanceLimit        = errors.New("balance limit exceeded")
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	ErrScheduledTransferNotFound  = errors.New("scheduled transfer not found")
+	ErrScheduledTransferNotActive = errors.New("scheduled transfer is not active")
+)
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key header. StatusCode is zero while the request is in progress.
+type IdempotencyRecord struct {
+	UserID       int
+	Key          string
+	Fingerprint  string
+	StatusCode   int
+	ResponseBody []byte
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+type IdempotencyRepository interface {
+	// Reserve stores a new in-progress record and returns it with true, or
+	// returns the existing record with false. Records older than ttl are
+	// discarded first.
+	Reserve(ctx context.Context, userID int, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, userID int, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, userID int, key string) error
+	// DeleteExpired removes the records of all users created before, and
+	// returns how many were removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
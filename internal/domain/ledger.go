@@ -0,0 +1,103 @@
+package domain
+
+import "context"
+
+// Kinds of ledger entries. Every change of a user balance is recorded as an
+// entry whose postings sum to zero.
+const (
+	LedgerKindOpening    = "opening"
+	LedgerKindGrant      = "grant"
+	LedgerKindTransfer   = "transfer"
+	LedgerKindPurchase   = "purchase"
+	LedgerKindRefund     = "refund"
+	LedgerKindAdjustment = "adjustment"
+	LedgerKindAllowance  = "allowance"
+)
+
+// Ledger accounts. Coins enter circulation from LedgerAccountIssuance and
+// are spent into LedgerAccountStore; LedgerAccountUser postings carry a user.
+const (
+	LedgerAccountUser     = "user"
+	LedgerAccountIssuance = "issuance"
+	LedgerAccountStore    = "store"
+)
+
+type LedgerPosting struct {
+	Account string
+	UserID  int
+	Amount  int
+}
+
+// LedgerEntry is a balanced set of postings. OrderID and TransactionID
+// reference the order or transfer that caused it, if any; ActorID and
+// Reason record who made a manual adjustment and why.
+type LedgerEntry struct {
+	Kind          string
+	OrderID       int
+	TransactionID int
+	ActorID       int
+	Reason        string
+	Postings      []LedgerPosting
+}
+
+// NewLedgerEntry moves amount coins from one account to another.
+func NewLedgerEntry(kind string, from, to LedgerPosting, amount int) LedgerEntry {
+	from.Amount = -amount
+	to.Amount = amount
+	return LedgerEntry{Kind: kind, Postings: []LedgerPosting{from, to}}
+}
+
+// UserAccount is the ledger account of a user.
+func UserAccount(userID int) LedgerPosting {
+	return LedgerPosting{Account: LedgerAccountUser, UserID: userID}
+}
+
+// SystemAccount is a ledger account that belongs to no user.
+func SystemAccount(account string) LedgerPosting {
+	return LedgerPosting{Account: account}
+}
+
+// Balanced reports whether the postings of the entry sum to zero.
+func (e *LedgerEntry) Balanced() bool {
+	sum := 0
+	for _, posting := range e.Postings {
+		sum += posting.Amount
+	}
+	return len(e.Postings) >= 2 && sum == 0
+}
+
+// BalanceDrift is a user whose stored balance differs from the sum of the
+// postings on their account.
+type BalanceDrift struct {
+	UserID        int
+	Username      string
+	Balance       int
+	LedgerBalance int
+}
+
+type LedgerReport struct {
+	CheckedUsers      int
+	Drifts            []BalanceDrift
+	UnbalancedEntries []int
+}
+
+func (r *LedgerReport) Consistent() bool {
+	return len(r.Drifts) == 0 && len(r.UnbalancedEntries) == 0
+}
+
+// BalanceAdjustment is a manual change of a user balance by an admin.
+// A positive Amount grants coins, a negative one deducts them.
+type BalanceAdjustment struct {
+	Username string
+	ActorID  int
+	Amount   int
+	Reason   string
+}
+
+type LedgerRepository interface {
+	// Adjust applies the adjustment and records it in the ledger.
+	Adjust(ctx context.Context, adjustment BalanceAdjustment) (*User, error)
+	// Reconcile recomputes user balances from the ledger and reports every
+	// mismatch with users.balance as well as entries that do not balance.
+	Reconcile(ctx context.Context) (*LedgerReport, error)
+}
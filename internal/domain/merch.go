@@ -1,10 +1,52 @@
 package domain
 
+import "context"
+
 type Merch struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Price int    `json:"price"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Price    int    `json:"price"`
+	Archived bool   `json:"archived"`
+	// Stock is nil for items that are not limited.
+	Stock *int `json:"stock"`
+}
+
+// StockChange is how an update changes the stock of an item. The zero value
+// keeps the current stock, so purchases made meanwhile are not overwritten.
+type StockChange struct {
+	// Set replaces the stock.
+	Set *int
+	// Delta is added to a limited stock, e.g. to restock or write off items.
+	Delta int
+	// Unlimited removes the limit.
+	Unlimited bool
+}
+
+// Apply returns the stock after the change. A delta applies only to a
+// limited stock and cannot take it below zero.
+func (c StockChange) Apply(stock *int) (*int, error) {
+	switch {
+	case c.Unlimited:
+		return nil, nil
+	case c.Set != nil:
+		return c.Set, nil
+	case c.Delta == 0:
+		return stock, nil
+	case stock == nil || *stock+c.Delta < 0:
+		return nil, ErrStockChange
+	}
+	changed := *stock + c.Delta
+	return &changed, nil
 }
 
+// MerchRepository manages the catalog. Archived items stay in the table so
+// that existing orders keep pointing at them, but can no longer be bought.
 type MerchRepository interface {
+	List(ctx context.Context, includeArchived bool) ([]Merch, error)
+	GetByID(ctx context.Context, id int) (*Merch, error)
+	Create(ctx context.Context, merch *Merch) (*Merch, error)
+	// Update replaces the name, price and archived flag and applies stock
+	// to the current stock.
+	Update(ctx context.Context, merch *Merch, stock StockChange) (*Merch, error)
+	Archive(ctx context.Context, id int) (*Merch, error)
 }
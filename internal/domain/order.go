@@ -1,12 +1,76 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// Order statuses. Store staff pick a placed order and then deliver it,
+// which makes it fulfilled. The owner can cancel the order until it is
+// delivered; a fulfilled order can still be refunded.
+const (
+	OrderStatusPlaced    = "placed"
+	OrderStatusPicked    = "picked"
+	OrderStatusFulfilled = "fulfilled"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
+var orderTransitions = map[string][]string{
+	OrderStatusPlaced:    {OrderStatusPicked, OrderStatusCancelled},
+	OrderStatusPicked:    {OrderStatusFulfilled, OrderStatusCancelled},
+	OrderStatusFulfilled: {OrderStatusRefunded},
+}
+
+// CanTransitionOrder reports whether an order in status from may move to status to.
+func CanTransitionOrder(from, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
 
 type Order struct {
-	ID     int    `json:"id"`
-	Owner  User   `json:"owner"`
-	Merch  Merch  `json:"merch"`
-	Status string `json:"status"`
+	ID               int         `json:"id"`
+	OwnerID          int         `json:"ownerId"`
+	OwnerName        string      `json:"owner"`
+	Items            []OrderItem `json:"items"`
+	TotalPrice       int         `json:"totalPrice"`
+	Status           string      `json:"status"`
+	DeliveryLocation string      `json:"deliveryLocation"`
+	CreatedAt        time.Time   `json:"createdAt"`
+}
+
+// OrderItem is a line of an order. Price is the unit price paid.
+type OrderItem struct {
+	MerchID   int    `json:"merchId"`
+	MerchName string `json:"merch"`
+	Quantity  int    `json:"quantity"`
+	Price     int    `json:"price"`
+}
+
+// OrderEvent is an entry of the order audit trail: who moved the order to
+// which status and when. ActorName is empty when the actor was deleted.
+type OrderEvent struct {
+	ID        int       `json:"id"`
+	OrderID   int       `json:"orderId"`
+	Status    string    `json:"status"`
+	ActorName string    `json:"actor"`
+	Location  string    `json:"location"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OrderTransition describes a status change requested by ActorID. A non-zero
+// OwnerID restricts the change to that user's orders. Location is recorded
+// on delivery.
+type OrderTransition struct {
+	OrderID  int
+	OwnerID  int
+	ActorID  int
+	To       string
+	Location string
 }
 
 type MerchAmount struct {
@@ -15,6 +79,19 @@ type MerchAmount struct {
 }
 
 type OrderRepository interface {
-	BuyMerch(ctx context.Context, userID int, merchName string) error
+	// PlaceOrder prices the items, takes them from stock and debits the
+	// owner in one transaction. It returns the order and the remaining balance.
+	PlaceOrder(ctx context.Context, userID int, items []OrderItem) (*Order, int, error)
+	GetUserOrders(ctx context.Context, userID int) ([]Order, error)
+	// GetPendingOrders lists up to limit placed and picked orders with an id
+	// above afterID, oldest first.
+	GetPendingOrders(ctx context.Context, afterID, limit int) ([]Order, error)
+	// TransitionOrder changes the order status and records it in the audit
+	// trail. Cancelling returns the items to stock; cancelling and refunding
+	// credit the price back to the owner.
+	TransitionOrder(ctx context.Context, change OrderTransition) (*Order, error)
+	GetOrderEvents(ctx context.Context, orderID int) ([]OrderEvent, error)
+	// GetUserMerchAmount counts the merch of the user's orders that were not
+	// cancelled or refunded.
 	GetUserMerchAmount(ctx context.Context, userID int) ([]MerchAmount, error)
 }
@@ -0,0 +1,44 @@
+package domain
+
+import "context"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID   int
+	Username string
+	Roles    []string
+	TokenID  string
+}
+
+type principalContextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// MustPrincipal returns the caller of a request served behind RequireAuth
+// and panics when the route was registered without it.
+func MustPrincipal(ctx context.Context) *Principal {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		panic("domain: no principal in context, route is missing RequireAuth")
+	}
+	return principal
+}
+
+// HasAnyRole reports whether the principal holds at least one of roles.
+func (p *Principal) HasAnyRole(roles ...string) bool {
+	for _, held := range p.Roles {
+		for _, role := range roles {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
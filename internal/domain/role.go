@@ -0,0 +1,18 @@
+package domain
+
+// Roles a user can hold. Every account has RoleUser; RoleTeamLead may
+// distribute coins to several users at once.
+const (
+	RoleUser         = "user"
+	RoleAdmin        = "admin"
+	RoleStoreManager = "store-manager"
+	RoleTeamLead     = "team-lead"
+)
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAdmin, RoleStoreManager, RoleTeamLead:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// How often a scheduled transfer repeats.
+const (
+	ScheduleRepeatOnce    = "once"
+	ScheduleRepeatWeekly  = "weekly"
+	ScheduleRepeatMonthly = "monthly"
+)
+
+// Scheduled transfer statuses. An active transfer waits for its next run; a
+// one-off transfer becomes completed or failed after it ran. Only the
+// sender can cancel it.
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusFailed    = "failed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+// Outcomes of a scheduled transfer run. A skipped run records why the
+// transfer could not be made, e.g. insufficient funds; a failed run records
+// an unexpected error, so the transfer does not block the scheduler.
+const (
+	ScheduledRunSucceeded = "succeeded"
+	ScheduledRunSkipped   = "skipped"
+	ScheduledRunFailed    = "failed"
+)
+
+// ScheduledTransfer sends Amount coins to the recipient at StartAt and,
+// unless Repeat is once, every week or month after it. RecipientUsername
+// is empty when the recipient was deleted.
+type ScheduledTransfer struct {
+	ID                int       `json:"id"`
+	SenderID          int       `json:"senderId"`
+	RecipientID       int       `json:"recipientId"`
+	RecipientUsername string    `json:"recipient"`
+	Amount            int       `json:"amount"`
+	Message           string    `json:"message"`
+	Repeat            string    `json:"repeat"`
+	StartAt           time.Time `json:"startAt"`
+	NextRunAt         time.Time `json:"nextRunAt"`
+	// Occurrence is the index of the next run, counting from zero.
+	Occurrence int       `json:"-"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ScheduledTransferRun is an attempt to execute a scheduled transfer.
+// TransactionID is set when it succeeded, Reason when it was skipped or failed.
+type ScheduledTransferRun struct {
+	ID                  int       `json:"id"`
+	ScheduledTransferID int       `json:"scheduledTransferId"`
+	ScheduledAt         time.Time `json:"scheduledAt"`
+	Status              string    `json:"status"`
+	TransactionID       int       `json:"transactionId"`
+	Reason              string    `json:"reason"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+func IsValidScheduleRepeat(repeat string) bool {
+	switch repeat {
+	case ScheduleRepeatOnce, ScheduleRepeatWeekly, ScheduleRepeatMonthly:
+		return true
+	}
+	return false
+}
+
+// ScheduledRunAt returns the time of the n-th run, counting from zero. Monthly
+// runs keep the day of StartAt in UTC and fall on the last day of shorter months.
+func (s *ScheduledTransfer) ScheduledRunAt(n int) time.Time {
+	start := s.StartAt.UTC()
+	switch s.Repeat {
+	case ScheduleRepeatWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case ScheduleRepeatMonthly:
+		firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(n), 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+		return firstOfMonth.AddDate(0, 0, min(start.Day(), lastDay)-1)
+	}
+	return start
+}
+
+// Advance moves the transfer past a run made at now. A one-off transfer ends
+// with status; a recurring one continues with its first occurrence after
+// now, so occurrences missed while the service was down are not caught up.
+func (s *ScheduledTransfer) Advance(now time.Time, succeeded bool) {
+	if s.Repeat == ScheduleRepeatOnce {
+		s.Status = ScheduledTransferStatusCompleted
+		if !succeeded {
+			s.Status = ScheduledTransferStatusFailed
+		}
+		return
+	}
+
+	s.Occurrence++
+	for !s.ScheduledRunAt(s.Occurrence).After(now) {
+		s.Occurrence++
+	}
+	s.NextRunAt = s.ScheduledRunAt(s.Occurrence)
+}
+
+type ScheduledTransferRepository interface {
+	Create(ctx context.Context, transfer *ScheduledTransfer) (*ScheduledTransfer, error)
+	GetUserScheduledTransfers(ctx context.Context, userID int) ([]ScheduledTransfer, error)
+	// Cancel stops an active transfer of the sender.
+	Cancel(ctx context.Context, senderID, id int) (*ScheduledTransfer, error)
+	GetRuns(ctx context.Context, senderID, id int) ([]ScheduledTransferRun, error)
+	// RunDue executes one transfer that is due at now, skipping transfers
+	// locked by another scheduler. It reports false when nothing is due.
+	RunDue(ctx context.Context, now time.Time) (*ScheduledTransferRun, bool, error)
+}
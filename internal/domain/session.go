@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Session is a refresh session; its ID is carried as the jti of the access
+// tokens issued for it, so revoking the session revokes those tokens as well.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    int       `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) (*Session, error)
+	Consume(ctx context.Context, tokenHash string) (*Session, error)
+	IsActive(ctx context.Context, id string) (bool, error)
+	Revoke(ctx context.Context, id string) error
+	RevokeAllForUser(ctx context.Context, userID int) error
+}
@@ -3,6 +3,52 @@ package domain
 import "github.com/golang-jwt/jwt/v5"
 
 type TokenClaims struct {
-	UserID int `json:"id"`
+	UserID   int      `json:"id"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
 	jwt.RegisteredClaims
 }
+
+func NewTokenClaims(principal *Principal) *TokenClaims {
+	return &TokenClaims{
+		UserID:   principal.UserID,
+		Username: principal.Username,
+		Roles:    principal.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID: principal.TokenID,
+		},
+	}
+}
+
+func (c *TokenClaims) Principal() *Principal {
+	return &Principal{
+		UserID:   c.UserID,
+		Username: c.Username,
+		Roles:    c.Roles,
+		TokenID:  c.ID,
+	}
+}
+
+// JWK is the public part of a signing key as published in the JWKS document.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// TokenSigner signs access tokens with its active key and verifies tokens
+// signed by any key it still trusts, identified by the kid header.
+type TokenSigner interface {
+	Sign(claims *TokenClaims) (string, error)
+	Verify(token string) (*TokenClaims, error)
+	PublicKeys() JWKS
+}
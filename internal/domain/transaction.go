@@ -1,15 +1,97 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// DeletedUsername is shown in place of a counterpart whose account no longer exists.
+const DeletedUsername = "deleted-user"
+
+// Kinds of coin movements recorded in the transactions table.
+const (
+	TransactionKindTransfer = "transfer"
+	// TransactionKindRefund credits the owner of a cancelled or refunded order.
+	TransactionKindRefund = "refund"
+)
+
+// Reactions the recipient of a transfer can leave on it.
+const (
+	ReactionThanks = "thanks"
+	ReactionHeart  = "heart"
+	ReactionClap   = "clap"
+	ReactionFire   = "fire"
+)
+
+// MaxTransferMessageLength is the longest memo, in characters, a transfer can carry.
+const MaxTransferMessageLength = 200
+
+// Directions of a transfer relative to the user whose history is read.
+const (
+	TransactionDirectionSent     = "sent"
+	TransactionDirectionReceived = "received"
+)
 
 type Transaction struct {
-	ID        int    `json:"id"`
-	Sender    int    `json:"sender"`
-	Recipient int    `json:"recipient"`
-	Amount    int    `json:"amount"`
+	ID                int       `json:"id"`
+	Sender            int       `json:"sender"`
+	SenderUsername    string    `json:"senderUsername"`
+	Recipient         int       `json:"recipient"`
+	RecipientUsername string    `json:"recipientUsername"`
+	Amount            int       `json:"amount"`
+	Message           string    `json:"message"`
+	Reaction          string    `json:"reaction"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+func IsValidReaction(reaction string) bool {
+	switch reaction {
+	case ReactionThanks, ReactionHeart, ReactionClap, ReactionFire:
+		return true
+	}
+	return false
+}
+
+// BulkTransfer is one entry of a bulk distribution of coins.
+type BulkTransfer struct {
+	ToUser  string
+	Amount  int
+	Message string
+}
+
+// BulkTransferResult reports the outcome of a BulkTransfer. Err is the
+// reason the entry was rejected; TransactionID is set once it was sent.
+type BulkTransferResult struct {
+	BulkTransfer
+	RecipientID   int
+	TransactionID int
+	Err           error
+}
+
+// TransactionFilter selects a page of the user's transfers, newest first.
+// Zero values leave a condition out: an empty Direction matches both
+// directions, zero From and To leave the date range open and a zero
+// BeforeID starts from the newest transfer.
+type TransactionFilter struct {
+	UserID       int
+	Direction    string
+	Counterparty string
+	From         time.Time
+	To           time.Time
+	BeforeID     int
+	Limit        int
 }
 
 type TransactionRepository interface {
-	SendCoinToUser(ctx context.Context, userID int, ToUser string, amount int) error
-	GetUserTransactions(ctx context.Context, userID int) ([]Transaction, error)
+	SendCoinToUser(ctx context.Context, userID int, ToUser string, amount int, message string) error
+	// SendCoinsBulk makes every transfer in one database transaction or none
+	// of them. It returns a result per transfer, even when it fails, and the
+	// sender balance after the transfers.
+	SendCoinsBulk(ctx context.Context, userID int, transfers []BulkTransfer) ([]BulkTransferResult, int, error)
+	// GetUserTransactions returns the transfers the user sent or received
+	// that match the filter.
+	GetUserTransactions(ctx context.Context, filter TransactionFilter) ([]Transaction, error)
+	// React sets the reaction of the recipient on a transfer they received.
+	// An empty reaction removes it.
+	React(ctx context.Context, recipientID, transactionID int, reaction string) error
 }
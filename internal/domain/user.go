@@ -2,16 +2,29 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
 type User struct {
-	ID             int    `json:"id"`
-	Username       string `json:"username"`
-	HashedPassword string `json:"hashedPassword"`
-	Balance        int    `json:"balance"`
+	ID             int       `json:"id"`
+	Username       string    `json:"username"`
+	HashedPassword string    `json:"hashedPassword"`
+	Balance        int       `json:"balance"`
+	Roles          []string  `json:"roles"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// LastLoginAt is nil until the user signs in for the first time.
+	LastLoginAt *time.Time `json:"lastLoginAt"`
 }
 
+// MaxBalance is the largest balance an account can hold.
+const MaxBalance = 100000000
+
 type UserRepository interface {
-	GetOrCreateByUsernamePassword(ctx context.Context, username, password string) (*User, error)
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	// Create registers a user with the given starting balance, granted
+	// through the ledger.
+	Create(ctx context.Context, username, hashedPassword string, balance int) (*User, error)
 	GetByID(ctx context.Context, id int) (*User, error)
+	SetRoles(ctx context.Context, username string, roles []string) (*User, error)
+	UpdateLastLogin(ctx context.Context, userID int) error
 }
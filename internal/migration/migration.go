@@ -0,0 +1,295 @@
+// Package migration evolves the database schema with versioned SQL files
+// embedded into the binary. Applied versions are recorded in the
+// schema_migrations table.
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey serializes concurrent migration runs with a transaction-level advisory lock.
+const lockKey = 7243501
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	database   *config.PostgresDb
+	migrations []Migration
+}
+
+func NewMigrator(db *config.PostgresDb) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{database: db, migrations: migrations}, nil
+}
+
+// load reads files named <version>_<name>.up.sql and <version>_<name>.down.sql.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", fileName)
+		}
+
+		versionPart, name, ok := strings.Cut(strings.TrimSuffix(fileName, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration file %s has no name", fileName)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s has invalid version", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("sql", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, migration.Name, name)
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order and returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range m.migrations {
+		ok, err := m.apply(ctx, migration)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied = append(applied, migration)
+		}
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) (applied bool, err error) {
+	tx, err := m.database.Connection.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockKey); err != nil {
+		return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)
+    `, migration.Version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", migration.Version, err)
+	}
+	if exists {
+		return false, tx.Rollback(ctx)
+	}
+
+	if _, err = tx.Exec(ctx, migration.Up); err != nil {
+		return false, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO schema_migrations (version, name)
+        VALUES ($1, $2)
+    `, migration.Version, migration.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	return true, nil
+}
+
+// Down reverts the most recently applied migration. It returns nil when
+// there is nothing to revert.
+func (m *Migrator) Down(ctx context.Context) (reverted *Migration, err error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, err := m.database.Connection.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockKey); err != nil {
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	var version int
+	err = tx.QueryRow(ctx, `
+        SELECT version
+        FROM schema_migrations
+        ORDER BY version DESC
+        LIMIT 1
+    `).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, tx.Rollback(ctx)
+		}
+		return nil, fmt.Errorf("failed to find last migration: %w", err)
+	}
+
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			reverted = &m.migrations[i]
+		}
+	}
+	if reverted == nil {
+		err = fmt.Errorf("applied migration %d is unknown to this binary", version)
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, reverted.Down); err != nil {
+		return nil, fmt.Errorf("failed to revert migration %d_%s: %w", reverted.Version, reverted.Name, err)
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version); err != nil {
+		return nil, fmt.Errorf("failed to unrecord migration %d: %w", version, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit migration %d: %w", version, err)
+	}
+
+	return reverted, nil
+}
+
+// Status lists every known migration with the time it was applied, if any.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.database.Connection.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over applied migrations: %w", err)
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.database.Connection.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INT PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+-- The schema created by the former setup scripts. IF NOT EXISTS lets
+-- databases created by them adopt this migration without losing data;
+-- the following migrations bring them to the current schema.
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    balance INT NOT NULL DEFAULT 0 CHECK (balance >= 0 AND balance <= 100000000)
+);
+CREATE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_id ON users (id);
+
+CREATE TABLE IF NOT EXISTS merch (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL UNIQUE,
+    price INT NOT NULL CHECK (price > 0 AND price <= 100000000)
+);
+CREATE INDEX IF NOT EXISTS idx_merch_name ON merch (name);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id SERIAL PRIMARY KEY,
+    sender INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0 AND amount <= 100000000)
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_sender ON transactions (sender);
+CREATE INDEX IF NOT EXISTS idx_transactions_recipient ON transactions (recipient);
+
+CREATE TABLE IF NOT EXISTS merch_orders (
+    id SERIAL PRIMARY KEY,
+    owner INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    merch INT NOT NULL REFERENCES merch(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_merch_orders_owner ON merch_orders (owner);
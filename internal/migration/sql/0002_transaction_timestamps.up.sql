@@ -0,0 +1,3 @@
+-- IF NOT EXISTS keeps this migration safe for databases created by the
+-- setup scripts after the column was added there.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,9 @@
+DELETE FROM transactions WHERE sender IS NULL OR recipient IS NULL;
+ALTER TABLE transactions DROP CONSTRAINT transactions_sender_fkey;
+ALTER TABLE transactions DROP CONSTRAINT transactions_recipient_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_sender_fkey
+    FOREIGN KEY (sender) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE transactions ADD CONSTRAINT transactions_recipient_fkey
+    FOREIGN KEY (recipient) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE transactions ALTER COLUMN sender SET NOT NULL;
+ALTER TABLE transactions ALTER COLUMN recipient SET NOT NULL;
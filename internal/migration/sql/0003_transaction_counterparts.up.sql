@@ -0,0 +1,10 @@
+-- Transfers outlive deleted accounts: the counterpart becomes NULL instead
+-- of the transfer disappearing from the other user's history.
+ALTER TABLE transactions ALTER COLUMN sender DROP NOT NULL;
+ALTER TABLE transactions ALTER COLUMN recipient DROP NOT NULL;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_sender_fkey;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_recipient_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_sender_fkey
+    FOREIGN KEY (sender) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD CONSTRAINT transactions_recipient_fkey
+    FOREIGN KEY (recipient) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE merch DROP COLUMN archived;
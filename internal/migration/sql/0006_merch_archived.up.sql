@@ -0,0 +1 @@
+ALTER TABLE merch ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
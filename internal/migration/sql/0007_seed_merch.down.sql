@@ -0,0 +1,4 @@
+-- Items that were already bought are kept so that orders stay intact.
+DELETE FROM merch
+WHERE name IN ('t-shirt', 'cup', 'book', 'pen', 'powerbank', 'hoody', 'umbrella', 'socks', 'wallet', 'pink-hoody')
+    AND NOT EXISTS (SELECT 1 FROM merch_orders mo WHERE mo.merch = merch.id);
@@ -0,0 +1,2 @@
+-- NULL stock means the item is not limited.
+ALTER TABLE merch ADD COLUMN stock INT CHECK (stock >= 0);
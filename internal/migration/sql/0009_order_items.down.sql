@@ -0,0 +1,14 @@
+-- Every unit becomes a separate single-item order again.
+ALTER TABLE merch_orders ADD COLUMN merch INT REFERENCES merch(id) ON DELETE CASCADE;
+
+INSERT INTO merch_orders (owner, merch, total_price)
+SELECT mo.owner, i.merch, i.price
+FROM merch_order_items i
+JOIN merch_orders mo ON mo.id = i.order_id
+CROSS JOIN generate_series(1, i.quantity);
+
+DELETE FROM merch_orders WHERE merch IS NULL;
+ALTER TABLE merch_orders ALTER COLUMN merch SET NOT NULL;
+ALTER TABLE merch_orders DROP COLUMN total_price;
+
+DROP TABLE merch_order_items;
@@ -0,0 +1,24 @@
+-- merch_orders becomes an order header; the bought items move to
+-- merch_order_items with the unit price paid at the time of purchase.
+CREATE TABLE merch_order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES merch_orders(id) ON DELETE CASCADE,
+    merch INT NOT NULL REFERENCES merch(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    price INT NOT NULL CHECK (price > 0 AND price <= 100000000)
+);
+CREATE INDEX idx_merch_order_items_order_id ON merch_order_items (order_id);
+
+INSERT INTO merch_order_items (order_id, merch, quantity, price)
+SELECT mo.id, mo.merch, 1, m.price
+FROM merch_orders mo
+JOIN merch m ON m.id = mo.merch;
+
+ALTER TABLE merch_orders ADD COLUMN total_price INT NOT NULL DEFAULT 0 CHECK (total_price >= 0);
+UPDATE merch_orders mo
+SET total_price = i.price
+FROM merch_order_items i
+WHERE i.order_id = mo.id;
+ALTER TABLE merch_orders ALTER COLUMN total_price DROP DEFAULT;
+
+ALTER TABLE merch_orders DROP COLUMN merch;
@@ -0,0 +1,5 @@
+DELETE FROM transactions WHERE kind <> 'transfer';
+ALTER TABLE transactions DROP COLUMN order_id;
+ALTER TABLE transactions DROP COLUMN kind;
+
+ALTER TABLE merch_orders DROP COLUMN status;
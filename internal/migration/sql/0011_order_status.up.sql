@@ -0,0 +1,11 @@
+-- Orders placed before statuses existed were handed out on purchase.
+ALTER TABLE merch_orders
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'fulfilled'
+    CHECK (status IN ('placed', 'fulfilled', 'cancelled', 'refunded'));
+ALTER TABLE merch_orders ALTER COLUMN status SET DEFAULT 'placed';
+
+-- Refunds credit the owner of an order and have no sender.
+ALTER TABLE transactions
+    ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'transfer'
+    CHECK (kind IN ('transfer', 'refund'));
+ALTER TABLE transactions ADD COLUMN order_id INT REFERENCES merch_orders(id) ON DELETE SET NULL;
@@ -0,0 +1,8 @@
+DROP TABLE merch_order_events;
+
+DROP INDEX idx_merch_orders_pending;
+ALTER TABLE merch_orders DROP COLUMN delivery_location;
+UPDATE merch_orders SET status = 'placed' WHERE status = 'picked';
+ALTER TABLE merch_orders DROP CONSTRAINT merch_orders_status_check;
+ALTER TABLE merch_orders ADD CONSTRAINT merch_orders_status_check
+    CHECK (status IN ('placed', 'fulfilled', 'cancelled', 'refunded'));
@@ -0,0 +1,15 @@
+ALTER TABLE merch_orders DROP CONSTRAINT merch_orders_status_check;
+ALTER TABLE merch_orders ADD CONSTRAINT merch_orders_status_check
+    CHECK (status IN ('placed', 'picked', 'fulfilled', 'cancelled', 'refunded'));
+ALTER TABLE merch_orders ADD COLUMN delivery_location VARCHAR(255);
+CREATE INDEX idx_merch_orders_pending ON merch_orders (id) WHERE status IN ('placed', 'picked');
+
+CREATE TABLE merch_order_events (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES merch_orders(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL,
+    actor INT REFERENCES users(id) ON DELETE SET NULL,
+    location VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_merch_order_events_order_id ON merch_order_events (order_id);
@@ -0,0 +1,4 @@
+CREATE INDEX idx_transactions_sender ON transactions (sender);
+CREATE INDEX idx_transactions_recipient ON transactions (recipient);
+DROP INDEX idx_transactions_sender_id;
+DROP INDEX idx_transactions_recipient_id;
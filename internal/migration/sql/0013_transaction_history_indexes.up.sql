@@ -0,0 +1,5 @@
+-- Keyset pagination of /api/transactions walks each side of the history by id.
+CREATE INDEX idx_transactions_sender_id ON transactions (sender, id);
+CREATE INDEX idx_transactions_recipient_id ON transactions (recipient, id);
+DROP INDEX IF EXISTS idx_transactions_sender;
+DROP INDEX IF EXISTS idx_transactions_recipient;
@@ -0,0 +1,2 @@
+ALTER TABLE merch_orders DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN created_at;
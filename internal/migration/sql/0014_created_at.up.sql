@@ -0,0 +1,13 @@
+-- transactions have had created_at since 0002. Existing users and orders get
+-- the migration time, except orders that already have an audit trail.
+ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE merch_orders ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE merch_orders mo
+SET created_at = e.placed_at
+FROM (
+    SELECT order_id, MIN(created_at) AS placed_at
+    FROM merch_order_events
+    GROUP BY order_id
+) e
+WHERE e.order_id = mo.id;
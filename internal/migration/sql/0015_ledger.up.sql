@@ -0,0 +1,32 @@
+CREATE TABLE ledger_entries (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL
+        CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund', 'adjustment')),
+    order_id INT REFERENCES merch_orders(id) ON DELETE SET NULL,
+    transaction_id INT REFERENCES transactions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE ledger_postings (
+    id SERIAL PRIMARY KEY,
+    entry_id INT NOT NULL REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    account VARCHAR(16) NOT NULL CHECK (account IN ('user', 'issuance', 'store')),
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    amount INT NOT NULL CHECK (amount <> 0)
+);
+CREATE INDEX idx_ledger_postings_entry_id ON ledger_postings (entry_id);
+CREATE INDEX idx_ledger_postings_user_id ON ledger_postings (user_id);
+
+-- Balances accumulated before the ledger existed become opening entries.
+DO $$
+DECLARE
+    u RECORD;
+    new_entry_id INT;
+BEGIN
+    FOR u IN SELECT id, balance FROM users WHERE balance > 0 ORDER BY id LOOP
+        INSERT INTO ledger_entries (kind) VALUES ('opening') RETURNING id INTO new_entry_id;
+        INSERT INTO ledger_postings (entry_id, account, user_id, amount)
+        VALUES (new_entry_id, 'issuance', NULL, -u.balance),
+               (new_entry_id, 'user', u.id, u.balance);
+    END LOOP;
+END $$;
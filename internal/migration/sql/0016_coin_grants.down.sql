@@ -0,0 +1,11 @@
+DROP TABLE allowance_runs;
+
+ALTER TABLE ledger_entries DROP COLUMN reason;
+ALTER TABLE ledger_entries DROP COLUMN actor;
+UPDATE ledger_entries SET kind = 'grant' WHERE kind = 'allowance';
+ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_kind_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_kind_check
+    CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund', 'adjustment'));
+
+DROP INDEX idx_users_last_login_at;
+ALTER TABLE users DROP COLUMN last_login_at;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMPTZ;
+CREATE INDEX idx_users_last_login_at ON users (last_login_at);
+
+ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_kind_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_kind_check
+    CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund', 'adjustment', 'allowance'));
+ALTER TABLE ledger_entries ADD COLUMN actor INT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE ledger_entries ADD COLUMN reason VARCHAR(255);
+
+-- One row per paid month keeps the allowance from being credited twice,
+-- even with several instances of the service running the job.
+CREATE TABLE allowance_runs (
+    period DATE PRIMARY KEY,
+    amount INT NOT NULL CHECK (amount > 0),
+    credited_users INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
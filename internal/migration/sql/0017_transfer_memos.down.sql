@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN reaction;
+ALTER TABLE transactions DROP COLUMN message;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN message VARCHAR(200);
+ALTER TABLE transactions ADD COLUMN reaction VARCHAR(16)
+    CHECK (reaction IN ('thanks', 'heart', 'clap', 'fire'));
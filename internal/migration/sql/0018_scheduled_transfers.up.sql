@@ -0,0 +1,30 @@
+CREATE TABLE scheduled_transfers (
+    id SERIAL PRIMARY KEY,
+    sender INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient INT REFERENCES users(id) ON DELETE SET NULL,
+    amount INT NOT NULL CHECK (amount > 0 AND amount <= 100000000),
+    message VARCHAR(200),
+    repeat VARCHAR(16) NOT NULL CHECK (repeat IN ('once', 'weekly', 'monthly')),
+    start_at TIMESTAMPTZ NOT NULL,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    occurrence INT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'completed', 'failed', 'cancelled')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The scheduler only looks at active transfers, earliest first.
+CREATE INDEX idx_scheduled_transfers_due ON scheduled_transfers (next_run_at) WHERE status = 'active';
+CREATE INDEX idx_scheduled_transfers_sender ON scheduled_transfers (sender, id);
+
+CREATE TABLE scheduled_transfer_runs (
+    id SERIAL PRIMARY KEY,
+    scheduled_transfer_id INT NOT NULL REFERENCES scheduled_transfers(id) ON DELETE CASCADE,
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('succeeded', 'skipped')),
+    transaction_id INT REFERENCES transactions(id) ON DELETE SET NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_scheduled_transfer_runs_transfer ON scheduled_transfer_runs (scheduled_transfer_id, id);
@@ -0,0 +1,9 @@
+ALTER TABLE transactions ADD COLUMN reaction VARCHAR(16)
+    CHECK (reaction IN ('thanks', 'heart', 'clap', 'fire'));
+
+UPDATE transactions t
+SET reaction = tr.reaction
+FROM transaction_reactions tr
+WHERE tr.transaction_id = t.id;
+
+DROP TABLE transaction_reactions;
@@ -0,0 +1,14 @@
+-- Reactions live apart from transactions, which are never updated once written.
+CREATE TABLE transaction_reactions (
+    transaction_id INT PRIMARY KEY REFERENCES transactions(id) ON DELETE CASCADE,
+    recipient_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reaction VARCHAR(16) NOT NULL CHECK (reaction IN ('thanks', 'heart', 'clap', 'fire')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO transaction_reactions (transaction_id, recipient_id, reaction)
+SELECT id, recipient, reaction
+FROM transactions
+WHERE reaction IS NOT NULL AND recipient IS NOT NULL;
+
+ALTER TABLE transactions DROP COLUMN reaction;
@@ -0,0 +1,3 @@
+ALTER TABLE scheduled_transfer_runs DROP CONSTRAINT scheduled_transfer_runs_status_check;
+ALTER TABLE scheduled_transfer_runs ADD CONSTRAINT scheduled_transfer_runs_status_check
+    CHECK (status IN ('succeeded', 'skipped', 'failed'));
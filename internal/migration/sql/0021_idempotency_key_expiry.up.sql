@@ -0,0 +1,2 @@
+-- Expired keys of all users are deleted by created_at.
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
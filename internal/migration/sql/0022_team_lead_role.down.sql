@@ -0,0 +1,4 @@
+UPDATE users SET roles = array_remove(roles, 'team-lead');
+ALTER TABLE users DROP CONSTRAINT users_roles_check;
+ALTER TABLE users ADD CONSTRAINT users_roles_check
+    CHECK (roles <@ ARRAY['user', 'admin', 'store-manager']::TEXT[]);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP CONSTRAINT users_roles_check;
+ALTER TABLE users ADD CONSTRAINT users_roles_check
+    CHECK (roles <@ ARRAY['user', 'admin', 'store-manager', 'team-lead']::TEXT[]);
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+type allowanceRepositoryImpl struct {
+	database *config.PostgresDb
+}
+
+func NewAllowanceRepository(db *config.PostgresDb) domain.AllowanceRepository {
+	return &allowanceRepositoryImpl{database: db}
+}
+
+// Credit claims the period in allowance_runs and pays every active user in
+// the same transaction. A concurrent run for the same period waits on the
+// primary key and then finds it taken. Users are locked in id order like
+// transfers do; those whose balance would pass the limit are skipped.
+func (r allowanceRepositoryImpl) Credit(ctx context.Context, period time.Time, amount int, activeSince time.Time) (run *domain.AllowanceRun, credited bool, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil || !credited {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	tag, err := tx.Exec(ctx, `
+        INSERT INTO allowance_runs (period, amount)
+        VALUES ($1, $2)
+        ON CONFLICT (period) DO NOTHING
+    `, period, amount)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim allowance period: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, false, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT id
+        FROM users
+        WHERE last_login_at >= $1 AND balance <= $2
+        ORDER BY id
+        FOR UPDATE
+    `, activeSince, domain.MaxBalance-amount)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to lock active users: %w", err)
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err = rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to iterate over active users: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE users
+        SET balance = balance + $1
+        WHERE id = ANY($2)
+    `, amount, userIDs)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to credit allowance: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		entry := domain.NewLedgerEntry(
+			domain.LedgerKindAllowance,
+			domain.SystemAccount(domain.LedgerAccountIssuance),
+			domain.UserAccount(userID),
+			amount,
+		)
+		if err = postLedgerEntry(ctx, tx, entry); err != nil {
+			return nil, false, err
+		}
+	}
+
+	run = &domain.AllowanceRun{Period: period, Amount: amount, CreditedUsers: len(userIDs)}
+	err = tx.QueryRow(ctx, `
+        UPDATE allowance_runs
+        SET credited_users = $2
+        WHERE period = $1
+        RETURNING created_at
+    `, period, run.CreditedUsers).Scan(&run.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record allowance run: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("allowance credited", slog.Time("period", period), slog.Int("users", run.CreditedUsers), slog.Int("amount", amount))
+	return run, true, nil
+}
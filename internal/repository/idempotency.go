@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+type idempotencyRepositoryImpl struct {
+	database *config.PostgresDb
+}
+
+func NewIdempotencyRepository(db *config.PostgresDb) domain.IdempotencyRepository {
+	return &idempotencyRepositoryImpl{database: db}
+}
+
+func (r idempotencyRepositoryImpl) Reserve(ctx context.Context, userID int, key, fingerprint string, ttl time.Duration) (*domain.IdempotencyRecord, bool, error) {
+	_, err := r.database.Connection.Exec(ctx, `
+        DELETE FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2 AND created_at < $3
+    `, userID, key, time.Now().Add(-ttl))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+	}
+
+	record := domain.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint}
+	err = r.database.Connection.QueryRow(ctx, `
+        INSERT INTO idempotency_keys (user_id, key, fingerprint)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, key) DO NOTHING
+        RETURNING user_id
+    `, userID, key, fingerprint).Scan(&record.UserID)
+	if err == nil {
+		return &record, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+
+	var statusCode *int
+	err = r.database.Connection.QueryRow(ctx, `
+        SELECT fingerprint, status_code, response_body
+        FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2
+    `, userID, key).Scan(&record.Fingerprint, &statusCode, &record.ResponseBody)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to select idempotency key: %w", err)
+	}
+	if statusCode != nil {
+		record.StatusCode = *statusCode
+	}
+
+	return &record, false, nil
+}
+
+func (r idempotencyRepositoryImpl) Complete(ctx context.Context, userID int, key string, statusCode int, body []byte) error {
+	_, err := r.database.Connection.Exec(ctx, `
+        UPDATE idempotency_keys
+        SET status_code = $3, response_body = $4
+        WHERE user_id = $1 AND key = $2
+    `, userID, key, statusCode, body)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r idempotencyRepositoryImpl) Release(ctx context.Context, userID int, key string) error {
+	_, err := r.database.Connection.Exec(ctx, `
+        DELETE FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2 AND status_code IS NULL
+    `, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r idempotencyRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.database.Connection.Exec(ctx, `
+        DELETE FROM idempotency_keys
+        WHERE created_at < $1
+    `, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
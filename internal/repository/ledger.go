@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+var errUnbalancedEntry = errors.New("ledger entry does not balance")
+
+type ledgerRepositoryImpl struct {
+	database *config.PostgresDb
+}
+
+func NewLedgerRepository(db *config.PostgresDb) domain.LedgerRepository {
+	return &ledgerRepositoryImpl{database: db}
+}
+
+// postLedgerEntry records the entry within the transaction that changes the balances.
+func postLedgerEntry(ctx context.Context, tx pgx.Tx, entry domain.LedgerEntry) error {
+	if !entry.Balanced() {
+		return fmt.Errorf("failed to post %s entry: %w", entry.Kind, errUnbalancedEntry)
+	}
+
+	var entryID int
+	err := tx.QueryRow(ctx, `
+        INSERT INTO ledger_entries (kind, order_id, transaction_id, actor, reason)
+        VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), NULLIF($4, 0), NULLIF($5, ''))
+        RETURNING id
+    `, entry.Kind, entry.OrderID, entry.TransactionID, entry.ActorID, entry.Reason).Scan(&entryID)
+	if err != nil {
+		return fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+
+	for _, posting := range entry.Postings {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO ledger_postings (entry_id, account, user_id, amount)
+            VALUES ($1, $2, NULLIF($3, 0), $4)
+        `, entryID, posting.Account, posting.UserID, posting.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to create ledger posting: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r ledgerRepositoryImpl) Adjust(ctx context.Context, adjustment domain.BalanceAdjustment) (user *domain.User, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	user = &domain.User{}
+	err = tx.QueryRow(ctx, `
+        SELECT id, username, balance, roles, created_at, last_login_at
+        FROM users
+        WHERE username = $1
+        FOR UPDATE
+    `, adjustment.Username).Scan(&user.ID, &user.Username, &user.Balance, &user.Roles, &user.CreatedAt, &user.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	balance := user.Balance + adjustment.Amount
+	if balance < 0 {
+		return nil, domain.ErrInsufficientFunds
+	}
+	if balance > domain.MaxBalance {
+		return nil, domain.ErrBalanceLimit
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET balance = $2 WHERE id = $1`, user.ID, balance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+	user.Balance = balance
+
+	issuance, account := domain.SystemAccount(domain.LedgerAccountIssuance), domain.UserAccount(user.ID)
+	entry := domain.NewLedgerEntry(domain.LedgerKindAdjustment, issuance, account, adjustment.Amount)
+	if adjustment.Amount < 0 {
+		entry = domain.NewLedgerEntry(domain.LedgerKindAdjustment, account, issuance, -adjustment.Amount)
+	}
+	entry.ActorID = adjustment.ActorID
+	entry.Reason = adjustment.Reason
+	if err = postLedgerEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("balance adjusted", slog.Int("userID", user.ID), slog.Int("amount", adjustment.Amount), slog.Int("actorID", adjustment.ActorID))
+	return user, nil
+}
+
+// Reconcile reads from a single snapshot, so balances changed by concurrent
+// requests are compared with the postings written alongside them.
+func (r ledgerRepositoryImpl) Reconcile(ctx context.Context) (report *domain.LedgerReport, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	report = &domain.LedgerReport{}
+
+	rows, err := tx.Query(ctx, `
+        SELECT u.id, u.username, u.balance, COALESCE(SUM(p.amount), 0)
+        FROM users u
+        LEFT JOIN ledger_postings p ON p.user_id = u.id AND p.account = 'user'
+        GROUP BY u.id
+        ORDER BY u.id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recompute balances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var drift domain.BalanceDrift
+		if err := rows.Scan(&drift.UserID, &drift.Username, &drift.Balance, &drift.LedgerBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance row: %w", err)
+		}
+		report.CheckedUsers++
+		if drift.Balance != drift.LedgerBalance {
+			report.Drifts = append(report.Drifts, drift)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over balances: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `
+        SELECT e.id
+        FROM ledger_entries e
+        LEFT JOIN ledger_postings p ON p.entry_id = e.id
+        GROUP BY e.id
+        HAVING COALESCE(SUM(p.amount), 0) <> 0 OR COUNT(p.id) < 2
+        ORDER BY e.id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int
+		if err := rows.Scan(&entryID); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		report.UnbalancedEntries = append(report.UnbalancedEntries, entryID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over ledger entries: %w", err)
+	}
+
+	return report, nil
+}
@@ -1,10 +1,19 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/eslupmi101/avito_merch_store/internal/config"
 	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// uniqueViolation is the PostgreSQL error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
 type merchRepositoryImpl struct {
 	database *config.PostgresDb
 }
@@ -12,3 +21,134 @@ type merchRepositoryImpl struct {
 func NewMerchRepository(db *config.PostgresDb) domain.MerchRepository {
 	return &merchRepositoryImpl{database: db}
 }
+
+func (r *merchRepositoryImpl) List(ctx context.Context, includeArchived bool) ([]domain.Merch, error) {
+	rows, err := r.database.Connection.Query(ctx, `
+        SELECT id, name, price, archived, stock
+        FROM merch
+        WHERE $1 OR NOT archived
+        ORDER BY name
+    `, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve merch: %w", err)
+	}
+	defer rows.Close()
+
+	merchList := []domain.Merch{}
+	for rows.Next() {
+		var merch domain.Merch
+		if err := rows.Scan(&merch.ID, &merch.Name, &merch.Price, &merch.Archived, &merch.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan merch row: %w", err)
+		}
+		merchList = append(merchList, merch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over merch: %w", err)
+	}
+
+	return merchList, nil
+}
+
+func (r *merchRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.Merch, error) {
+	var merch domain.Merch
+	err := r.database.Connection.QueryRow(ctx, `
+        SELECT id, name, price, archived, stock
+        FROM merch
+        WHERE id = $1
+    `, id).Scan(&merch.ID, &merch.Name, &merch.Price, &merch.Archived, &merch.Stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrMerchNotFound
+		}
+		return nil, fmt.Errorf("failed to select merch: %w", err)
+	}
+
+	return &merch, nil
+}
+
+func (r *merchRepositoryImpl) Create(ctx context.Context, newMerch *domain.Merch) (*domain.Merch, error) {
+	var merch domain.Merch
+	err := r.database.Connection.QueryRow(ctx, `
+        INSERT INTO merch (name, price, stock)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (name) DO NOTHING
+        RETURNING id, name, price, archived, stock
+    `, newMerch.Name, newMerch.Price, newMerch.Stock).Scan(&merch.ID, &merch.Name, &merch.Price, &merch.Archived, &merch.Stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrMerchAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to insert merch: %w", err)
+	}
+
+	return &merch, nil
+}
+
+// Update locks the item, so the stock change applies to the stock left
+// after concurrent purchases.
+func (r *merchRepositoryImpl) Update(ctx context.Context, merch *domain.Merch, change domain.StockChange) (updated *domain.Merch, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var stock *int
+	err = tx.QueryRow(ctx, `
+        SELECT stock FROM merch WHERE id = $1 FOR UPDATE
+    `, merch.ID).Scan(&stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrMerchNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch merch: %w", err)
+	}
+	if stock, err = change.Apply(stock); err != nil {
+		return nil, err
+	}
+
+	updated = &domain.Merch{}
+	err = tx.QueryRow(ctx, `
+        UPDATE merch
+        SET name = $2, price = $3, archived = $4, stock = $5
+        WHERE id = $1
+        RETURNING id, name, price, archived, stock
+    `, merch.ID, merch.Name, merch.Price, merch.Archived, stock).
+		Scan(&updated.ID, &updated.Name, &updated.Price, &updated.Archived, &updated.Stock)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, domain.ErrMerchAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to update merch: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *merchRepositoryImpl) Archive(ctx context.Context, id int) (*domain.Merch, error) {
+	var merch domain.Merch
+	err := r.database.Connection.QueryRow(ctx, `
+        UPDATE merch
+        SET archived = TRUE
+        WHERE id = $1
+        RETURNING id, name, price, archived, stock
+    `, id).Scan(&merch.ID, &merch.Name, &merch.Price, &merch.Archived, &merch.Stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrMerchNotFound
+		}
+		return nil, fmt.Errorf("failed to archive merch: %w", err)
+	}
+
+	return &merch, nil
+}
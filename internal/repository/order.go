@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 
 	"github.com/eslupmi101/avito_merch_store/internal/config"
 	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type orderRepositoryImpl struct {
@@ -19,10 +21,18 @@ func NewOrderRepository(db *config.PostgresDb) domain.OrderRepository {
 	return &orderRepositoryImpl{database: db}
 }
 
-func (r orderRepositoryImpl) BuyMerch(ctx context.Context, userID int, merchName string) (err error) {
-	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+// maxOrderPrice is the largest balance a user can have, so dearer orders
+// can never be paid for.
+const maxOrderPrice = domain.MaxBalance
+
+// PlaceOrder takes the items from stock, debits the total price and records
+// the order in a single transaction. All updates are conditional, so read
+// committed is enough and concurrent buyers wait for each other instead of
+// failing to serialize. Items must not repeat merch names.
+func (r orderRepositoryImpl) PlaceOrder(ctx context.Context, userID int, items []domain.OrderItem) (order *domain.Order, balance int, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	defer func() {
@@ -32,64 +42,396 @@ func (r orderRepositoryImpl) BuyMerch(ctx context.Context, userID int, merchName
 		}
 	}()
 
-	var merch domain.Merch
+	order = &domain.Order{OwnerID: userID, Status: domain.OrderStatusPlaced}
+	for _, item := range items {
+		var stock *int
+		err = tx.QueryRow(ctx, `
+            SELECT id, price, stock
+            FROM merch
+            WHERE name = $1 AND NOT archived
+        `, item.MerchName).Scan(&item.MerchID, &item.Price, &stock)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				slog.Info("merch does not exist", slog.String("merchName", item.MerchName))
+				return nil, 0, domain.ErrMerchNotFound
+			}
+			return nil, 0, fmt.Errorf("failed to fetch merch: %w", err)
+		}
+
+		if stock != nil && *stock < item.Quantity {
+			slog.Info("merch is out of stock", slog.String("merchName", item.MerchName))
+			return nil, 0, domain.ErrOutOfStock
+		}
+
+		order.Items = append(order.Items, item)
+		order.TotalPrice += item.Price * item.Quantity
+	}
+
+	if order.TotalPrice > maxOrderPrice {
+		slog.Info("insufficient funds", slog.Int("userID", userID), slog.Int("totalPrice", order.TotalPrice))
+		return nil, 0, domain.ErrInsufficientFunds
+	}
+
+	// Lock merch rows in id order so that concurrent orders cannot deadlock.
+	lockOrder := make([]domain.OrderItem, len(order.Items))
+	copy(lockOrder, order.Items)
+	sort.Slice(lockOrder, func(i, j int) bool {
+		return lockOrder[i].MerchID < lockOrder[j].MerchID
+	})
+	for _, item := range lockOrder {
+		var cmdTag pgconn.CommandTag
+		cmdTag, err = tx.Exec(ctx, `
+            UPDATE merch
+            SET stock = stock - $2
+            WHERE id = $1 AND (stock IS NULL OR stock >= $2)
+        `, item.MerchID, item.Quantity)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to update merch stock: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			slog.Info("merch is out of stock", slog.String("merchName", item.MerchName))
+			return nil, 0, domain.ErrOutOfStock
+		}
+	}
+
 	err = tx.QueryRow(ctx, `
-        SELECT id, name, price 
-        FROM merch 
-        WHERE name = $1
-    `, merchName).Scan(&merch.ID, &merch.Name, &merch.Price)
+        UPDATE users
+        SET balance = balance - $1
+        WHERE id = $2 AND balance >= $1
+        RETURNING balance
+    `, order.TotalPrice, userID).Scan(&balance)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			slog.Info("merch does not exist", slog.String("merchName", merchName))
-			return errors.New("merch does not exist")
+			slog.Info("insufficient funds", slog.Int("userID", userID), slog.Int("totalPrice", order.TotalPrice))
+			return nil, 0, domain.ErrInsufficientFunds
 		}
-		return fmt.Errorf("failed to fetch merch: %w", err)
+		return nil, 0, fmt.Errorf("failed to update user balance: %w", err)
 	}
 
-	cmdTag, err := tx.Exec(ctx, `
-        UPDATE users 
-        SET balance = balance - $1 
-        WHERE id = $2 AND balance >= $1
-    `, merch.Price, userID)
+	err = tx.QueryRow(ctx, `
+        INSERT INTO merch_orders (owner, total_price)
+        VALUES ($1, $2)
+        RETURNING id, created_at
+    `, userID, order.TotalPrice).Scan(&order.ID, &order.CreatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to update user balance: %w", err)
+		return nil, 0, fmt.Errorf("failed to create merch order: %w", err)
+	}
+
+	for _, item := range order.Items {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO merch_order_items (order_id, merch, quantity, price)
+            VALUES ($1, $2, $3, $4)
+        `, order.ID, item.MerchID, item.Quantity, item.Price)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create merch order item: %w", err)
+		}
+	}
+
+	if err = insertOrderEvent(ctx, tx, order.ID, domain.OrderStatusPlaced, userID, ""); err != nil {
+		return nil, 0, err
 	}
-	if cmdTag.RowsAffected() == 0 {
-		slog.Info("insufficient funds", slog.Int("userID", userID), slog.Int("merchPrice", merch.Price))
-		return errors.New("insufficient funds")
+
+	entry := domain.NewLedgerEntry(
+		domain.LedgerKindPurchase,
+		domain.UserAccount(userID),
+		domain.SystemAccount(domain.LedgerAccountStore),
+		order.TotalPrice,
+	)
+	entry.OrderID = order.ID
+	if err = postLedgerEntry(ctx, tx, entry); err != nil {
+		return nil, 0, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	var orderID int
+	slog.Info("merch order placed", slog.Int("userID", userID), slog.Int("orderID", order.ID), slog.Int("totalPrice", order.TotalPrice))
+	return order, balance, nil
+}
+
+func (r orderRepositoryImpl) GetUserOrders(ctx context.Context, userID int) ([]domain.Order, error) {
+	return r.queryOrders(ctx, `
+        SELECT mo.id, mo.owner, u.username, mo.total_price, mo.status, COALESCE(mo.delivery_location, ''), mo.created_at
+        FROM merch_orders mo
+        JOIN users u ON u.id = mo.owner
+        WHERE mo.owner = $1
+        ORDER BY mo.id DESC
+    `, userID)
+}
+
+func (r orderRepositoryImpl) GetPendingOrders(ctx context.Context, afterID, limit int) ([]domain.Order, error) {
+	return r.queryOrders(ctx, `
+        SELECT mo.id, mo.owner, u.username, mo.total_price, mo.status, COALESCE(mo.delivery_location, ''), mo.created_at
+        FROM merch_orders mo
+        JOIN users u ON u.id = mo.owner
+        WHERE mo.status IN ('placed', 'picked') AND mo.id > $1
+        ORDER BY mo.id
+        LIMIT $2
+    `, afterID, limit)
+}
+
+func (r orderRepositoryImpl) queryOrders(ctx context.Context, query string, args ...any) ([]domain.Order, error) {
+	rows, err := r.database.Connection.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []domain.Order{}
+	for rows.Next() {
+		var order domain.Order
+		err := rows.Scan(
+			&order.ID, &order.OwnerID, &order.OwnerName, &order.TotalPrice,
+			&order.Status, &order.DeliveryLocation, &order.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order row: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over orders: %w", err)
+	}
+
+	for i := range orders {
+		orders[i].Items, err = getOrderItems(ctx, r.database.Connection, orders[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return orders, nil
+}
+
+func (r orderRepositoryImpl) TransitionOrder(ctx context.Context, change domain.OrderTransition) (order *domain.Order, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	order = &domain.Order{ID: change.OrderID}
 	err = tx.QueryRow(ctx, `
-        INSERT INTO merch_orders (owner, merch) 
-        VALUES ($1, $2) 
-        RETURNING id
-    `, userID, merch.ID).Scan(&orderID)
+        SELECT mo.owner, u.username, mo.total_price, mo.status, COALESCE(mo.delivery_location, ''), mo.created_at
+        FROM merch_orders mo
+        JOIN users u ON u.id = mo.owner
+        WHERE mo.id = $1
+        FOR UPDATE OF mo
+    `, change.OrderID).Scan(
+		&order.OwnerID, &order.OwnerName, &order.TotalPrice, &order.Status, &order.DeliveryLocation, &order.CreatedAt,
+	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			slog.Error("failed to create merch order", slog.String("error", "no order created"))
-			return errors.New("order creation failed")
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch order: %w", err)
+	}
+	if change.OwnerID != 0 && order.OwnerID != change.OwnerID {
+		return nil, domain.ErrOrderNotFound
+	}
+	if !domain.CanTransitionOrder(order.Status, change.To) {
+		slog.Info("order transition rejected", slog.Int("orderID", order.ID), slog.String("from", order.Status), slog.String("to", change.To))
+		return nil, domain.ErrOrderTransition
+	}
+
+	order.Items, err = getOrderItems(ctx, tx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if change.Location != "" {
+		order.DeliveryLocation = change.Location
+	}
+	_, err = tx.Exec(ctx, `
+        UPDATE merch_orders
+        SET status = $2, delivery_location = NULLIF($3, '')
+        WHERE id = $1
+    `, order.ID, change.To, order.DeliveryLocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+	order.Status = change.To
+
+	if err = insertOrderEvent(ctx, tx, order.ID, change.To, change.ActorID, change.Location); err != nil {
+		return nil, err
+	}
+
+	// Merch rows are locked in id order before the user row, as in PlaceOrder.
+	if change.To == domain.OrderStatusCancelled {
+		_, err = tx.Exec(ctx, `
+            SELECT m.id
+            FROM merch m
+            WHERE m.id IN (SELECT merch FROM merch_order_items WHERE order_id = $1)
+            ORDER BY m.id
+            FOR UPDATE
+        `, order.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock merch: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+            UPDATE merch m
+            SET stock = m.stock + i.quantity
+            FROM merch_order_items i
+            WHERE i.order_id = $1 AND m.id = i.merch AND m.stock IS NOT NULL
+        `, order.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restock merch: %w", err)
+		}
+	}
+
+	if change.To == domain.OrderStatusCancelled || change.To == domain.OrderStatusRefunded {
+		var balance int
+		err = tx.QueryRow(ctx, `
+            SELECT balance FROM users WHERE id = $1 FOR UPDATE
+        `, order.OwnerID).Scan(&balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock order owner: %w", err)
+		}
+		if balance > domain.MaxBalance-order.TotalPrice {
+			slog.Info("refund exceeds balance limit", slog.Int("orderID", order.ID), slog.Int("userID", order.OwnerID))
+			return nil, domain.ErrBalanceLimit
+		}
+
+		_, err = tx.Exec(ctx, `
+            UPDATE users
+            SET balance = balance + $1
+            WHERE id = $2
+        `, order.TotalPrice, order.OwnerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to credit order owner: %w", err)
+		}
+
+		var transactionID int
+		err = tx.QueryRow(ctx, `
+            INSERT INTO transactions (sender, recipient, amount, kind, order_id)
+            VALUES (NULL, $1, $2, $3, $4)
+            RETURNING id
+        `, order.OwnerID, order.TotalPrice, domain.TransactionKindRefund, order.ID).Scan(&transactionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record refund: %w", err)
+		}
+
+		entry := domain.NewLedgerEntry(
+			domain.LedgerKindRefund,
+			domain.SystemAccount(domain.LedgerAccountStore),
+			domain.UserAccount(order.OwnerID),
+			order.TotalPrice,
+		)
+		entry.OrderID = order.ID
+		entry.TransactionID = transactionID
+		if err = postLedgerEntry(ctx, tx, entry); err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("failed to create merch order: %w", err)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	slog.Info("merch purchase successful", slog.Int("userID", userID), slog.Int("merchID", merch.ID), slog.Int("orderID", orderID))
+	slog.Info("order status changed", slog.Int("orderID", order.ID), slog.String("status", order.Status), slog.Int("actorID", change.ActorID))
+	return order, nil
+}
+
+func (r orderRepositoryImpl) GetOrderEvents(ctx context.Context, orderID int) ([]domain.OrderEvent, error) {
+	var exists bool
+	err := r.database.Connection.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM merch_orders WHERE id = $1)
+    `, orderID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check order: %w", err)
+	}
+	if !exists {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	rows, err := r.database.Connection.Query(ctx, `
+        SELECT e.id, e.order_id, e.status, COALESCE(u.username, ''), COALESCE(e.location, ''), e.created_at
+        FROM merch_order_events e
+        LEFT JOIN users u ON u.id = e.actor
+        WHERE e.order_id = $1
+        ORDER BY e.id
+    `, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve order events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.OrderEvent{}
+	for rows.Next() {
+		var event domain.OrderEvent
+		err := rows.Scan(&event.ID, &event.OrderID, &event.Status, &event.ActorName, &event.Location, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order event row: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over order events: %w", err)
+	}
+
+	return events, nil
+}
+
+func insertOrderEvent(ctx context.Context, tx pgx.Tx, orderID int, status string, actorID int, location string) error {
+	_, err := tx.Exec(ctx, `
+        INSERT INTO merch_order_events (order_id, status, actor, location)
+        VALUES ($1, $2, $3, NULLIF($4, ''))
+    `, orderID, status, actorID, location)
+	if err != nil {
+		return fmt.Errorf("failed to record order event: %w", err)
+	}
 	return nil
 }
 
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func getOrderItems(ctx context.Context, q querier, orderID int) ([]domain.OrderItem, error) {
+	rows, err := q.Query(ctx, `
+        SELECT m.id, m.name, i.quantity, i.price
+        FROM merch_order_items i
+        JOIN merch m ON m.id = i.merch
+        WHERE i.order_id = $1
+        ORDER BY i.id
+    `, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve order items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.OrderItem
+	for rows.Next() {
+		var item domain.OrderItem
+		if err := rows.Scan(&item.MerchID, &item.MerchName, &item.Quantity, &item.Price); err != nil {
+			return nil, fmt.Errorf("failed to scan order item row: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over order items: %w", err)
+	}
+
+	return items, nil
+}
+
 func (r orderRepositoryImpl) GetUserMerchAmount(ctx context.Context, userID int) ([]domain.MerchAmount, error) {
 	var merchAmounts []domain.MerchAmount
 
 	rows, err := r.database.Connection.Query(
 		ctx, `
-            SELECT m.name, COUNT(*) AS amount
+            SELECT m.name, SUM(i.quantity) AS amount
             FROM merch_orders mo
-            JOIN merch m ON mo.merch = m.id
-            WHERE mo.owner = $1
+            JOIN merch_order_items i ON i.order_id = mo.id
+            JOIN merch m ON i.merch = m.id
+            WHERE mo.owner = $1 AND mo.status IN ('placed', 'picked', 'fulfilled')
             GROUP BY m.name
         `, userID)
 	if err != nil {
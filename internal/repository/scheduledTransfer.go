@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// skippableTransferErrors leave a scheduled run skipped instead of failing the scheduler.
+var skippableTransferErrors = []error{
+	domain.ErrInsufficientFunds,
+	domain.ErrRecipientNotFound,
+	domain.ErrUserNotFound,
+	domain.ErrBalanceLimit,
+}
+
+// maxRunReasonLength is the size of scheduled_transfer_runs.reason.
+const maxRunReasonLength = 255
+
+type scheduledTransferRepositoryImpl struct {
+	database *config.PostgresDb
+}
+
+func NewScheduledTransferRepository(db *config.PostgresDb) domain.ScheduledTransferRepository {
+	return &scheduledTransferRepositoryImpl{database: db}
+}
+
+const scheduledTransferColumns = `st.id, st.sender, COALESCE(st.recipient, 0), COALESCE(u.username, ''),
+            st.amount, COALESCE(st.message, ''), st.repeat, st.start_at, st.next_run_at,
+            st.occurrence, st.status, st.created_at`
+
+func scanScheduledTransfer(row pgx.Row) (*domain.ScheduledTransfer, error) {
+	var transfer domain.ScheduledTransfer
+	err := row.Scan(
+		&transfer.ID, &transfer.SenderID, &transfer.RecipientID, &transfer.RecipientUsername,
+		&transfer.Amount, &transfer.Message, &transfer.Repeat, &transfer.StartAt, &transfer.NextRunAt,
+		&transfer.Occurrence, &transfer.Status, &transfer.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// Create resolves the recipient by RecipientUsername and schedules the
+// first run at StartAt.
+func (r scheduledTransferRepositoryImpl) Create(ctx context.Context, transfer *domain.ScheduledTransfer) (*domain.ScheduledTransfer, error) {
+	var recipientID int
+	err := r.database.Connection.QueryRow(ctx, `
+        SELECT id FROM users WHERE username = $1
+    `, transfer.RecipientUsername).Scan(&recipientID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrRecipientNotFound
+		}
+		return nil, fmt.Errorf("failed to find recipient: %w", err)
+	}
+	if recipientID == transfer.SenderID {
+		return nil, domain.ErrSelfTransfer
+	}
+
+	created, err := scanScheduledTransfer(r.database.Connection.QueryRow(ctx, `
+        WITH st AS (
+            INSERT INTO scheduled_transfers (sender, recipient, amount, message, repeat, start_at, next_run_at)
+            VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $6)
+            RETURNING *
+        )
+        SELECT `+scheduledTransferColumns+`
+        FROM st
+        LEFT JOIN users u ON u.id = st.recipient
+    `, transfer.SenderID, recipientID, transfer.Amount, transfer.Message, transfer.Repeat, transfer.StartAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+
+	slog.Info("transfer scheduled", slog.Int("userID", created.SenderID), slog.Int("scheduledTransferID", created.ID))
+	return created, nil
+}
+
+func (r scheduledTransferRepositoryImpl) GetUserScheduledTransfers(ctx context.Context, userID int) ([]domain.ScheduledTransfer, error) {
+	rows, err := r.database.Connection.Query(ctx, `
+        SELECT `+scheduledTransferColumns+`
+        FROM scheduled_transfers st
+        LEFT JOIN users u ON u.id = st.recipient
+        WHERE st.sender = $1
+        ORDER BY st.id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve scheduled transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []domain.ScheduledTransfer{}
+	for rows.Next() {
+		transfer, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transfer row: %w", err)
+		}
+		transfers = append(transfers, *transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over scheduled transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r scheduledTransferRepositoryImpl) Cancel(ctx context.Context, senderID, id int) (transfer *domain.ScheduledTransfer, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	transfer, err = scanScheduledTransfer(tx.QueryRow(ctx, `
+        SELECT `+scheduledTransferColumns+`
+        FROM scheduled_transfers st
+        LEFT JOIN users u ON u.id = st.recipient
+        WHERE st.id = $1 AND st.sender = $2
+        FOR UPDATE OF st
+    `, id, senderID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrScheduledTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch scheduled transfer: %w", err)
+	}
+	if transfer.Status != domain.ScheduledTransferStatusActive {
+		return nil, domain.ErrScheduledTransferNotActive
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE scheduled_transfers
+        SET status = $2
+        WHERE id = $1
+    `, id, domain.ScheduledTransferStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel scheduled transfer: %w", err)
+	}
+	transfer.Status = domain.ScheduledTransferStatusCancelled
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("scheduled transfer cancelled", slog.Int("userID", senderID), slog.Int("scheduledTransferID", id))
+	return transfer, nil
+}
+
+func (r scheduledTransferRepositoryImpl) GetRuns(ctx context.Context, senderID, id int) ([]domain.ScheduledTransferRun, error) {
+	var exists bool
+	err := r.database.Connection.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM scheduled_transfers WHERE id = $1 AND sender = $2)
+    `, id, senderID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check scheduled transfer: %w", err)
+	}
+	if !exists {
+		return nil, domain.ErrScheduledTransferNotFound
+	}
+
+	rows, err := r.database.Connection.Query(ctx, `
+        SELECT id, scheduled_transfer_id, scheduled_at, status,
+            COALESCE(transaction_id, 0), COALESCE(reason, ''), created_at
+        FROM scheduled_transfer_runs
+        WHERE scheduled_transfer_id = $1
+        ORDER BY id DESC
+    `, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve scheduled transfer runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []domain.ScheduledTransferRun{}
+	for rows.Next() {
+		var run domain.ScheduledTransferRun
+		err := rows.Scan(
+			&run.ID, &run.ScheduledTransferID, &run.ScheduledAt, &run.Status,
+			&run.TransactionID, &run.Reason, &run.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transfer run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over scheduled transfer runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// RunDue claims the earliest due transfer with FOR UPDATE SKIP LOCKED, so
+// several schedulers never run the same transfer twice. The coins move
+// through transferCoins inside a savepoint: when the transfer is not
+// possible, the savepoint is rolled back and the run is recorded as skipped
+// with the reason. Any other error is recorded as a failed run, and the
+// transfer advances as usual so it does not stay due.
+func (r scheduledTransferRepositoryImpl) RunDue(ctx context.Context, now time.Time) (run *domain.ScheduledTransferRun, ran bool, err error) {
+	tx, err := r.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil || !ran {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	transfer, err := scanScheduledTransfer(tx.QueryRow(ctx, `
+        SELECT `+scheduledTransferColumns+`
+        FROM scheduled_transfers st
+        LEFT JOIN users u ON u.id = st.recipient
+        WHERE st.status = 'active' AND st.next_run_at <= $1
+        ORDER BY st.next_run_at, st.id
+        LIMIT 1
+        FOR UPDATE OF st SKIP LOCKED
+    `, now))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to claim scheduled transfer: %w", err)
+	}
+
+	run = &domain.ScheduledTransferRun{
+		ScheduledTransferID: transfer.ID,
+		ScheduledAt:         transfer.NextRunAt,
+		Status:              domain.ScheduledRunSucceeded,
+	}
+	run.TransactionID, err = r.transfer(ctx, tx, transfer)
+	if err != nil {
+		run.Status = domain.ScheduledRunSkipped
+		if !isSkippableTransferError(err) {
+			slog.Error("scheduled transfer failed", slog.Int("scheduledTransferID", transfer.ID), slog.String("error", err.Error()))
+			run.Status = domain.ScheduledRunFailed
+		}
+		run.Reason = truncateReason(err.Error())
+	}
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO scheduled_transfer_runs (scheduled_transfer_id, scheduled_at, status, transaction_id, reason)
+        VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''))
+        RETURNING id, created_at
+    `, run.ScheduledTransferID, run.ScheduledAt, run.Status, run.TransactionID, run.Reason).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record scheduled transfer run: %w", err)
+	}
+
+	transfer.Advance(now, run.Status == domain.ScheduledRunSucceeded)
+	_, err = tx.Exec(ctx, `
+        UPDATE scheduled_transfers
+        SET status = $2, occurrence = $3, next_run_at = $4
+        WHERE id = $1
+    `, transfer.ID, transfer.Status, transfer.Occurrence, transfer.NextRunAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to advance scheduled transfer: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("scheduled transfer ran", slog.Int("scheduledTransferID", transfer.ID), slog.String("status", run.Status), slog.String("reason", run.Reason))
+	return run, true, nil
+}
+
+func (r scheduledTransferRepositoryImpl) transfer(ctx context.Context, tx pgx.Tx, transfer *domain.ScheduledTransfer) (transactionID int, err error) {
+	if transfer.RecipientID == 0 {
+		return 0, domain.ErrRecipientNotFound
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = savepoint.Rollback(ctx)
+		}
+	}()
+
+	transactionID, err = transferCoins(ctx, savepoint, transfer.SenderID, transfer.RecipientID, transfer.Amount, transfer.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = savepoint.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return transactionID, nil
+}
+
+// truncateReason fits an error message into scheduled_transfer_runs.reason.
+func truncateReason(reason string) string {
+	runes := []rune(reason)
+	if len(runes) > maxRunReasonLength {
+		return string(runes[:maxRunReasonLength])
+	}
+	return reason
+}
+
+func isSkippableTransferError(err error) bool {
+	for _, skippable := range skippableTransferErrors {
+		if errors.Is(err, skippable) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+type sessionRepositoryImpl struct {
+	database *config.PostgresDb
+}
+
+func NewSessionRepository(db *config.PostgresDb) domain.SessionRepository {
+	return &sessionRepositoryImpl{database: db}
+}
+
+func (r sessionRepositoryImpl) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) (*domain.Session, error) {
+	session := domain.Session{UserID: userID, ExpiresAt: expiresAt}
+	err := r.database.Connection.QueryRow(ctx, `
+        INSERT INTO refresh_sessions (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)
+        RETURNING id::text
+    `, userID, tokenHash, expiresAt).Scan(&session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Consume revokes the active session matching tokenHash and returns it, so
+// that every refresh token can be exchanged only once.
+func (r sessionRepositoryImpl) Consume(ctx context.Context, tokenHash string) (*domain.Session, error) {
+	var session domain.Session
+	err := r.database.Connection.QueryRow(ctx, `
+        UPDATE refresh_sessions
+        SET revoked_at = NOW()
+        WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+        RETURNING id::text, user_id, expires_at
+    `, tokenHash).Scan(&session.ID, &session.UserID, &session.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to consume refresh session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (r sessionRepositoryImpl) IsActive(ctx context.Context, id string) (bool, error) {
+	var active bool
+	err := r.database.Connection.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1
+            FROM refresh_sessions
+            WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+        )
+    `, id).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check refresh session: %w", err)
+	}
+
+	return active, nil
+}
+
+func (r sessionRepositoryImpl) Revoke(ctx context.Context, id string) error {
+	_, err := r.database.Connection.Exec(ctx, `
+        UPDATE refresh_sessions
+        SET revoked_at = NOW()
+        WHERE id = $1 AND revoked_at IS NULL
+    `, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh session: %w", err)
+	}
+
+	return nil
+}
+
+func (r sessionRepositoryImpl) RevokeAllForUser(ctx context.Context, userID int) error {
+	_, err := r.database.Connection.Exec(ctx, `
+        UPDATE refresh_sessions
+        SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh sessions: %w", err)
+	}
+
+	return nil
+}
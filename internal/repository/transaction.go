@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 
 	"github.com/eslupmi101/avito_merch_store/internal/config"
 	"github.com/eslupmi101/avito_merch_store/internal/domain"
@@ -18,7 +21,9 @@ func NewTransactionRepository(db *config.PostgresDb) domain.TransactionRepositor
 	return &transactionRepositoryImpl{database: db}
 }
 
-func (tr transactionRepositoryImpl) SendCoinToUser(ctx context.Context, userID int, toUser string, amount int) (err error) {
+// SendCoinToUser resolves the recipient before changing anything and then
+// moves the coins with transferCoins.
+func (tr transactionRepositoryImpl) SendCoinToUser(ctx context.Context, userID int, toUser string, amount int, message string) (err error) {
 	tx, err := tr.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
 	if err != nil {
 		return err
@@ -29,43 +34,257 @@ func (tr transactionRepositoryImpl) SendCoinToUser(ctx context.Context, userID i
 		}
 	}()
 
-	result, err := tx.Exec(ctx, `
-        UPDATE users
-        SET balance = balance - $1
-        WHERE id = $2 AND balance >= $1
-    `, amount, userID)
+	var recipientID int
+	err = tx.QueryRow(ctx, `SELECT id FROM users WHERE username = $1`, toUser).Scan(&recipientID)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrRecipientNotFound
+		}
+		return fmt.Errorf("failed to find recipient: %w", err)
+	}
+	if recipientID == userID {
+		return domain.ErrSelfTransfer
+	}
+
+	if _, err = transferCoins(ctx, tx, userID, recipientID, amount, message); err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		return errors.New("insufficient funds")
+
+	return tx.Commit(ctx)
+}
+
+// SendCoinsBulk resolves every recipient first and locks the sender and all
+// recipients in id order before moving any coins, so it cannot deadlock with
+// other transfers. A rejected entry rolls back the whole batch. The results
+// are returned on every error, with no TransactionID set.
+func (tr transactionRepositoryImpl) SendCoinsBulk(ctx context.Context, userID int, transfers []domain.BulkTransfer) (results []domain.BulkTransferResult, balance int, err error) {
+	results = make([]domain.BulkTransferResult, len(transfers))
+	usernames := make([]string, len(transfers))
+	total := 0
+	for i, transfer := range transfers {
+		results[i].BulkTransfer = transfer
+		usernames[i] = transfer.ToUser
+		total += transfer.Amount
 	}
 
-	var recipientID int
-	err = tx.QueryRow(ctx, `
+	tx, err := tr.database.Connection.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return results, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			// Transfers made before the failure were rolled back with it.
+			for i := range results {
+				results[i].TransactionID = 0
+			}
+		}
+	}()
+
+	rows, err := tx.Query(ctx, `SELECT id, username FROM users WHERE username = ANY($1)`, usernames)
+	if err != nil {
+		return results, 0, fmt.Errorf("failed to find recipients: %w", err)
+	}
+	recipientIDs := map[string]int{}
+	for rows.Next() {
+		var id int
+		var username string
+		if err = rows.Scan(&id, &username); err != nil {
+			rows.Close()
+			return results, 0, fmt.Errorf("failed to scan recipient row: %w", err)
+		}
+		recipientIDs[username] = id
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return results, 0, fmt.Errorf("failed to find recipients: %w", err)
+	}
+
+	parties := []int{userID}
+	for i := range results {
+		id, ok := recipientIDs[results[i].ToUser]
+		switch {
+		case !ok:
+			results[i].Err = domain.ErrRecipientNotFound
+		case id == userID:
+			results[i].Err = domain.ErrSelfTransfer
+		default:
+			results[i].RecipientID = id
+			parties = append(parties, id)
+			continue
+		}
+		if err == nil {
+			err = results[i].Err
+		}
+	}
+	if err != nil {
+		return results, 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+        SELECT id
+        FROM users
+        WHERE id = ANY($1)
+        ORDER BY id
+        FOR UPDATE
+    `, parties)
+	if err != nil {
+		return results, 0, fmt.Errorf("failed to lock users: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `SELECT balance FROM users WHERE id = $1`, userID).Scan(&balance)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return results, 0, domain.ErrUserNotFound
+		}
+		return results, 0, fmt.Errorf("failed to fetch sender balance: %w", err)
+	}
+	if balance < total {
+		slog.Info("insufficient funds for bulk transfer", slog.Int("userID", userID), slog.Int("total", total))
+		return results, balance, domain.ErrInsufficientFunds
+	}
+
+	for i := range results {
+		result := &results[i]
+		result.TransactionID, err = transferCoins(ctx, tx, userID, result.RecipientID, result.Amount, result.Message)
+		if err != nil {
+			if errors.Is(err, domain.ErrBalanceLimit) {
+				result.Err = err
+				return results, balance, err
+			}
+			return results, 0, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return results, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("bulk transfer sent", slog.Int("userID", userID), slog.Int("recipients", len(results)), slog.Int("total", total))
+	return results, balance - total, nil
+}
+
+// transferCoins moves coins between two users within tx and returns the id
+// of the recorded transfer. Both users are locked in id order, so two users
+// sending to each other at the same time wait for one another instead of
+// deadlocking. Every way of sending coins goes through it.
+func transferCoins(ctx context.Context, tx pgx.Tx, senderID, recipientID, amount int, message string) (int, error) {
+	rows, err := tx.Query(ctx, `
+        SELECT id, balance
+        FROM users
+        WHERE id = ANY($1)
+        ORDER BY id
+        FOR UPDATE
+    `, []int{senderID, recipientID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock users: %w", err)
+	}
+	balances := map[int]int{}
+	for rows.Next() {
+		var id, balance int
+		if err = rows.Scan(&id, &balance); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		balances[id] = balance
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to lock users: %w", err)
+	}
+
+	senderBalance, ok := balances[senderID]
+	if !ok {
+		return 0, domain.ErrUserNotFound
+	}
+	recipientBalance, ok := balances[recipientID]
+	if !ok {
+		return 0, domain.ErrRecipientNotFound
+	}
+	if senderBalance < amount {
+		return 0, domain.ErrInsufficientFunds
+	}
+	if recipientBalance > domain.MaxBalance-amount {
+		return 0, domain.ErrBalanceLimit
+	}
+
+	_, err = tx.Exec(ctx, `
         UPDATE users
-        SET balance = balance + $1
-        WHERE username = $2
+        SET balance = balance + CASE WHEN id = $2 THEN -$1 ELSE $1 END
+        WHERE id IN ($2, $3)
+    `, amount, senderID, recipientID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move coins: %w", err)
+	}
+
+	var transactionID int
+	err = tx.QueryRow(ctx, `
+        INSERT INTO transactions (sender, recipient, amount, message)
+        VALUES ($1, $2, $3, NULLIF($4, ''))
         RETURNING id
-    `, amount, toUser).Scan(&recipientID)
+    `, senderID, recipientID, amount, message).Scan(&transactionID)
 	if err != nil {
-		return errors.New("toUser does not exist")
+		return 0, fmt.Errorf("failed to record transaction: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	entry := domain.NewLedgerEntry(domain.LedgerKindTransfer, domain.UserAccount(senderID), domain.UserAccount(recipientID), amount)
+	entry.TransactionID = transactionID
+	if err = postLedgerEntry(ctx, tx, entry); err != nil {
+		return 0, err
+	}
+
+	return transactionID, nil
 }
 
-func (r transactionRepositoryImpl) GetUserTransactions(ctx context.Context, userID int) ([]domain.Transaction, error) {
+func (r transactionRepositoryImpl) GetUserTransactions(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
 	var transactions []domain.Transaction
 
-	rows, err := r.database.Connection.Query(
-		ctx,
-		`SELECT id, sender, recipient, amount
-		FROM transactions
-		WHERE sender = $1 OR recipient = $1
-		ORDER BY id DESC`,
-		userID,
-	)
+	args := []any{filter.UserID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"t.kind = 'transfer'"}
+	switch filter.Direction {
+	case domain.TransactionDirectionSent:
+		conditions = append(conditions, "t.sender = $1")
+	case domain.TransactionDirectionReceived:
+		conditions = append(conditions, "t.recipient = $1")
+	default:
+		conditions = append(conditions, "(t.sender = $1 OR t.recipient = $1)")
+	}
+	if filter.Counterparty != "" {
+		placeholder := arg(filter.Counterparty)
+		conditions = append(conditions, fmt.Sprintf(
+			"((t.sender = $1 AND r.username = %[1]s) OR (t.recipient = $1 AND s.username = %[1]s))", placeholder,
+		))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "t.created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "t.created_at < "+arg(filter.To))
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "t.id < "+arg(filter.BeforeID))
+	}
+
+	query := `SELECT t.id,
+			COALESCE(t.sender, 0), COALESCE(s.username, ''),
+			COALESCE(t.recipient, 0), COALESCE(r.username, ''),
+			t.amount, COALESCE(t.message, ''), COALESCE(tr.reaction, ''), t.created_at
+		FROM transactions t
+		LEFT JOIN users s ON s.id = t.sender
+		LEFT JOIN users r ON r.id = t.recipient
+		LEFT JOIN transaction_reactions tr ON tr.transaction_id = t.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY t.id DESC`
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := r.database.Connection.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve transactions: %w", err)
 	}
@@ -73,7 +292,12 @@ func (r transactionRepositoryImpl) GetUserTransactions(ctx context.Context, user
 
 	for rows.Next() {
 		var transaction domain.Transaction
-		err := rows.Scan(&transaction.ID, &transaction.Sender, &transaction.Recipient, &transaction.Amount)
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.Sender, &transaction.SenderUsername,
+			&transaction.Recipient, &transaction.RecipientUsername,
+			&transaction.Amount, &transaction.Message, &transaction.Reaction, &transaction.CreatedAt,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction row: %w", err)
 		}
@@ -86,3 +310,42 @@ func (r transactionRepositoryImpl) GetUserTransactions(ctx context.Context, user
 
 	return transactions, nil
 }
+
+// React stores the recipient's reaction next to the transfer; an empty
+// reaction removes it. The transaction row itself is never updated.
+func (r transactionRepositoryImpl) React(ctx context.Context, recipientID, transactionID int, reaction string) error {
+	var exists bool
+	err := r.database.Connection.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM transactions
+            WHERE id = $1 AND recipient = $2 AND kind = 'transfer'
+        )
+    `, transactionID, recipientID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check transaction: %w", err)
+	}
+	if !exists {
+		return domain.ErrTransactionNotFound
+	}
+
+	if reaction == "" {
+		_, err = r.database.Connection.Exec(ctx, `
+            DELETE FROM transaction_reactions WHERE transaction_id = $1
+        `, transactionID)
+		if err != nil {
+			return fmt.Errorf("failed to remove reaction: %w", err)
+		}
+		return nil
+	}
+
+	_, err = r.database.Connection.Exec(ctx, `
+        INSERT INTO transaction_reactions (transaction_id, recipient_id, reaction)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (transaction_id) DO UPDATE
+        SET reaction = EXCLUDED.reaction, created_at = NOW()
+    `, transactionID, recipientID, reaction)
+	if err != nil {
+		return fmt.Errorf("failed to save reaction: %w", err)
+	}
+	return nil
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/eslupmi101/avito_merch_store/internal/config"
 	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	"github.com/jackc/pgx/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type userRepositoryImpl struct {
@@ -19,58 +18,115 @@ func NewUserRepository(db *config.PostgresDb) domain.UserRepository {
 	return &userRepositoryImpl{database: db}
 }
 
-func (r *userRepositoryImpl) GetOrCreateByUsernamePassword(ctx context.Context, username, password string) (*domain.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (r *userRepositoryImpl) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	var user domain.User
+	err := r.database.Connection.QueryRow(
+		ctx,
+		`SELECT id, username, password, balance, roles, created_at, last_login_at FROM users WHERE username = $1`,
+		username,
+	).Scan(&user.ID, &user.Username, &user.HashedPassword, &user.Balance, &user.Roles, &user.CreatedAt, &user.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to select user: %w", err)
+	}
 
+	return &user, nil
+}
+
+func (r *userRepositoryImpl) Create(ctx context.Context, username, hashedPassword string, balance int) (user *domain.User, err error) {
+	tx, err := r.database.Connection.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate hashed password: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
 
-	insertQuery := `
+	user = &domain.User{}
+	err = tx.QueryRow(ctx, `
 		INSERT INTO users (username, password, balance)
-		VALUES ($1, $2, 10000000)
+		VALUES ($1, $2, $3)
 		ON CONFLICT (username) DO NOTHING
-		RETURNING id, username, password, balance
-	`
-	var user domain.User
-	err = r.database.Connection.QueryRow(ctx, insertQuery, username, string(hashedPassword)).
-		Scan(&user.ID, &user.Username, &user.HashedPassword, &user.Balance)
+		RETURNING id, username, password, balance, roles, created_at, last_login_at
+	`, username, hashedPassword, balance).
+		Scan(&user.ID, &user.Username, &user.HashedPassword, &user.Balance, &user.Roles, &user.CreatedAt, &user.LastLoginAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			selectQuery := `SELECT id, username, password, balance FROM users WHERE username = $1`
-			err = r.database.Connection.QueryRow(ctx, selectQuery, username).
-				Scan(&user.ID, &user.Username, &user.HashedPassword, &user.Balance)
-			if err != nil {
-				return nil, fmt.Errorf("failed to select existing user: %w", err)
-			}
-
-			if bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password)) != nil {
-				return nil, errors.New("invalid username or password")
-			}
-			return &user, nil
+			return nil, domain.ErrUserAlreadyExists
 		}
 		return nil, fmt.Errorf("failed to insert user: %w", err)
 	}
 
-	return &user, nil
+	if user.Balance > 0 {
+		err = postLedgerEntry(ctx, tx, domain.NewLedgerEntry(
+			domain.LedgerKindGrant,
+			domain.SystemAccount(domain.LedgerAccountIssuance),
+			domain.UserAccount(user.ID),
+			user.Balance,
+		))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
 }
 
 func (r userRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.User, error) {
 	row := r.database.Connection.QueryRow(
 		ctx,
-		`SELECT id, username, password, balance FROM users WHERE id = $1`,
+		`SELECT id, username, password, balance, roles, created_at, last_login_at FROM users WHERE id = $1`,
 		id,
 	)
 
 	var user domain.User
 	var hashedPassword string
-	err := row.Scan(&user.ID, &user.Username, &hashedPassword, &user.Balance)
+	err := row.Scan(&user.ID, &user.Username, &hashedPassword, &user.Balance, &user.Roles, &user.CreatedAt, &user.LastLoginAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+func (r *userRepositoryImpl) SetRoles(ctx context.Context, username string, roles []string) (*domain.User, error) {
+	var user domain.User
+	err := r.database.Connection.QueryRow(ctx, `
+		UPDATE users
+		SET roles = $2
+		WHERE username = $1
+		RETURNING id, username, balance, roles, created_at, last_login_at
+	`, username, roles).
+		Scan(&user.ID, &user.Username, &user.Balance, &user.Roles, &user.CreatedAt, &user.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update user roles: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *userRepositoryImpl) UpdateLastLogin(ctx context.Context, userID int) error {
+	_, err := r.database.Connection.Exec(ctx, `
+		UPDATE users
+		SET last_login_at = NOW()
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update last login: %w", err)
+	}
+	return nil
+}
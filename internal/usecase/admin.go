@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+)
+
+type admin struct {
+	userRepository    domain.UserRepository
+	sessionRepository domain.SessionRepository
+	ledgerRepository  domain.LedgerRepository
+	contextTimeout    time.Duration
+}
+
+func NewAdmin(
+	userRepository domain.UserRepository,
+	sessionRepository domain.SessionRepository,
+	ledgerRepository domain.LedgerRepository,
+	timeout time.Duration,
+) domainAPI.AdminUsecase {
+	return &admin{
+		userRepository:    userRepository,
+		sessionRepository: sessionRepository,
+		ledgerRepository:  ledgerRepository,
+		contextTimeout:    timeout,
+	}
+}
+
+// SetUserRoles replaces the roles of a user. Every account keeps the user
+// role. Sessions of the user are revoked so that tokens carrying the old
+// roles stop working immediately.
+func (a *admin) SetUserRoles(ctx context.Context, username string, roles []string) (*domainAPI.UserRolesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.contextTimeout)
+	defer cancel()
+
+	user, err := a.userRepository.SetRoles(ctx, username, normalizeRoles(roles))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.sessionRepository.RevokeAllForUser(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return &domainAPI.UserRolesResponse{
+		Username: user.Username,
+		Roles:    user.Roles,
+	}, nil
+}
+
+func (a *admin) GrantCoins(ctx context.Context, adminID int, username string, request *domainAPI.CoinAdjustmentRequest) (*domainAPI.BalanceResponse, error) {
+	return a.adjust(ctx, adminID, username, request.Amount, request.Reason)
+}
+
+func (a *admin) DeductCoins(ctx context.Context, adminID int, username string, request *domainAPI.CoinAdjustmentRequest) (*domainAPI.BalanceResponse, error) {
+	return a.adjust(ctx, adminID, username, -request.Amount, request.Reason)
+}
+
+func (a *admin) adjust(ctx context.Context, adminID int, username string, amount int, reason string) (*domainAPI.BalanceResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.contextTimeout)
+	defer cancel()
+
+	user, err := a.ledgerRepository.Adjust(ctx, domain.BalanceAdjustment{
+		Username: username,
+		ActorID:  adminID,
+		Amount:   amount,
+		Reason:   reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainAPI.BalanceResponse{
+		Username: user.Username,
+		Coins:    user.Balance,
+	}, nil
+}
+
+func normalizeRoles(roles []string) []string {
+	normalized := []string{domain.RoleUser}
+	seen := map[string]bool{domain.RoleUser: true}
+	for _, role := range roles {
+		if !seen[role] {
+			seen[role] = true
+			normalized = append(normalized, role)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+)
+
+type allowance struct {
+	allowanceRepository domain.AllowanceRepository
+	amount              int
+	activeWithin        time.Duration
+	contextTimeout      time.Duration
+}
+
+func NewAllowance(
+	allowanceRepository domain.AllowanceRepository,
+	amount int,
+	activeWithin time.Duration,
+	timeout time.Duration,
+) domainAPI.AllowanceUsecase {
+	return &allowance{
+		allowanceRepository: allowanceRepository,
+		amount:              amount,
+		activeWithin:        activeWithin,
+		contextTimeout:      timeout,
+	}
+}
+
+// CreditDue counts users as active if they logged in within activeWithin
+// before now.
+func (a *allowance) CreditDue(ctx context.Context, now time.Time) (*domain.AllowanceRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.contextTimeout)
+	defer cancel()
+
+	run, credited, err := a.allowanceRepository.Credit(ctx, domain.AllowancePeriod(now), a.amount, now.Add(-a.activeWithin))
+	if err != nil || !credited {
+		return nil, err
+	}
+	return run, nil
+}
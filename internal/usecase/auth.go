@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/eslupmi101/avito_merch_store/internal/domain"
@@ -10,23 +11,171 @@ import (
 )
 
 type auth struct {
-	userRepository domain.UserRepository
-	contextTimeout time.Duration
+	userRepository    domain.UserRepository
+	sessionRepository domain.SessionRepository
+	passwordHasher    *utility.PasswordHasher
+	tokenSigner       domain.TokenSigner
+	accessTokenTTL    time.Duration
+	refreshTokenTTL   time.Duration
+	startingBalance   int
+	contextTimeout    time.Duration
 }
 
-func NewAuth(userRepository domain.UserRepository, timeout time.Duration) domainAPI.AuthUsecase {
+func NewAuth(
+	userRepository domain.UserRepository,
+	sessionRepository domain.SessionRepository,
+	passwordHasher *utility.PasswordHasher,
+	tokenSigner domain.TokenSigner,
+	accessTokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	startingBalance int,
+	timeout time.Duration,
+) domainAPI.AuthUsecase {
 	return &auth{
-		userRepository: userRepository,
-		contextTimeout: timeout,
+		userRepository:    userRepository,
+		sessionRepository: sessionRepository,
+		passwordHasher:    passwordHasher,
+		tokenSigner:       tokenSigner,
+		accessTokenTTL:    accessTokenTTL,
+		refreshTokenTTL:   refreshTokenTTL,
+		startingBalance:   startingBalance,
+		contextTimeout:    timeout,
 	}
 }
 
+// GetOrCreateByUsernamePassword hashes the password only when the user
+// actually has to be created; existing users pay for a single comparison.
 func (au *auth) GetOrCreateByUsernamePassword(ctx context.Context, username, password string) (*domain.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, au.contextTimeout)
 	defer cancel()
-	return au.userRepository.GetOrCreateByUsernamePassword(ctx, username, password)
+
+	user, err := au.login(ctx, username, password)
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return user, err
+	}
+
+	user, err = au.register(ctx, username, password)
+	if errors.Is(err, domain.ErrUserAlreadyExists) {
+		// Lost the race against a concurrent registration of the same username.
+		user, err = au.login(ctx, username, password)
+	}
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrInvalidCredentials
+	}
+	return user, err
 }
 
-func (auth *auth) CreateToken(userID int, secretKey string) (string, error) {
-	return utility.CreateToken(userID, secretKey)
+func (au *auth) Login(ctx context.Context, username, password string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, au.contextTimeout)
+	defer cancel()
+
+	user, err := au.login(ctx, username, password)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrInvalidCredentials
+	}
+	return user, err
+}
+
+func (au *auth) Register(ctx context.Context, username, password string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, au.contextTimeout)
+	defer cancel()
+
+	return au.register(ctx, username, password)
+}
+
+// CreateTokens opens a refresh session for the user and issues an access token bound to it.
+func (au *auth) CreateTokens(ctx context.Context, user *domain.User) (*domainAPI.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, au.contextTimeout)
+	defer cancel()
+
+	return au.createTokens(ctx, user)
+}
+
+// Refresh exchanges a refresh token for a new token pair, revoking the old session.
+func (au *auth) Refresh(ctx context.Context, refreshToken string) (*domainAPI.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, au.contextTimeout)
+	defer cancel()
+
+	session, err := au.sessionRepository.Consume(ctx, utility.HashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := au.userRepository.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return au.createTokens(ctx, user)
+}
+
+func (au *auth) Logout(ctx context.Context, userID int, sessionID string, allSessions bool) error {
+	ctx, cancel := context.WithTimeout(ctx, au.contextTimeout)
+	defer cancel()
+
+	if allSessions {
+		return au.sessionRepository.RevokeAllForUser(ctx, userID)
+	}
+	return au.sessionRepository.Revoke(ctx, sessionID)
+}
+
+// createTokens also marks the user as active for the monthly allowance.
+func (au *auth) createTokens(ctx context.Context, user *domain.User) (*domainAPI.AuthResponse, error) {
+	refreshToken, refreshTokenHash, err := utility.CreateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := au.userRepository.UpdateLastLogin(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	session, err := au.sessionRepository.Create(ctx, user.ID, refreshTokenHash, time.Now().Add(au.refreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &domain.Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Roles:    user.Roles,
+		TokenID:  session.ID,
+	}
+
+	token, err := utility.CreateToken(au.tokenSigner, principal, au.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainAPI.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(au.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (au *auth) login(ctx context.Context, username, password string) (*domain.User, error) {
+	user, err := au.userRepository.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := au.passwordHasher.Compare(ctx, user.HashedPassword, password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+func (au *auth) register(ctx context.Context, username, password string) (*domain.User, error) {
+	hashedPassword, err := au.passwordHasher.Hash(ctx, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return au.userRepository.Create(ctx, username, hashedPassword, au.startingBalance)
 }
@@ -24,5 +24,6 @@ func (o *buy) BuyMerch(ctx context.Context, userID int, merchName string) error
 	ctx, cancel := context.WithTimeout(ctx, o.contextTimeout)
 	defer cancel()
 
-	return o.orderRepository.BuyMerch(ctx, userID, merchName)
+	_, _, err := o.orderRepository.PlaceOrder(ctx, userID, []domain.OrderItem{{MerchName: merchName, Quantity: 1}})
+	return err
 }
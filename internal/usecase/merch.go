@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+)
+
+type merch struct {
+	merchRepository domain.MerchRepository
+	contextTimeout  time.Duration
+}
+
+func NewMerch(merchRepository domain.MerchRepository, timeout time.Duration) domainAPI.MerchUsecase {
+	return &merch{
+		merchRepository: merchRepository,
+		contextTimeout:  timeout,
+	}
+}
+
+// GetCatalog lists the items that can currently be bought.
+func (m *merch) GetCatalog(ctx context.Context) (*domainAPI.CatalogResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.contextTimeout)
+	defer cancel()
+
+	merchList, err := m.merchRepository.List(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domainAPI.CatalogItem, 0, len(merchList))
+	for _, merch := range merchList {
+		items = append(items, domainAPI.CatalogItem{
+			Name:  merch.Name,
+			Price: merch.Price,
+			Stock: merch.Stock,
+		})
+	}
+
+	return &domainAPI.CatalogResponse{Items: items}, nil
+}
+
+// List returns every item including archived ones.
+func (m *merch) List(ctx context.Context) ([]domain.Merch, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.contextTimeout)
+	defer cancel()
+
+	return m.merchRepository.List(ctx, true)
+}
+
+func (m *merch) Create(ctx context.Context, request *domainAPI.MerchRequest) (*domain.Merch, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.contextTimeout)
+	defer cancel()
+
+	return m.merchRepository.Create(ctx, &domain.Merch{
+		Name:  request.Name,
+		Price: request.Price,
+		Stock: request.Stock,
+	})
+}
+
+func (m *merch) Update(ctx context.Context, id int, request *domainAPI.MerchRequest) (*domain.Merch, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.contextTimeout)
+	defer cancel()
+
+	return m.merchRepository.Update(ctx, &domain.Merch{
+		ID:       id,
+		Name:     request.Name,
+		Price:    request.Price,
+		Archived: request.Archived,
+	}, domain.StockChange{
+		Set:       request.Stock,
+		Delta:     request.StockDelta,
+		Unlimited: request.Unlimited,
+	})
+}
+
+func (m *merch) Archive(ctx context.Context, id int) (*domain.Merch, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.contextTimeout)
+	defer cancel()
+
+	return m.merchRepository.Archive(ctx, id)
+}
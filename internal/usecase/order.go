@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+)
+
+type orders struct {
+	orderRepository domain.OrderRepository
+	contextTimeout  time.Duration
+}
+
+func NewOrders(orderRepository domain.OrderRepository, timeout time.Duration) domainAPI.OrderUsecase {
+	return &orders{
+		orderRepository: orderRepository,
+		contextTimeout:  timeout,
+	}
+}
+
+// PlaceOrder buys the whole cart at once. Lines naming the same merch are
+// merged into one.
+func (o *orders) PlaceOrder(ctx context.Context, userID int, request *domainAPI.OrderRequest) (*domainAPI.OrderResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.contextTimeout)
+	defer cancel()
+
+	var items []domain.OrderItem
+	index := map[string]int{}
+	for _, line := range request.Items {
+		if i, ok := index[line.Merch]; ok {
+			items[i].Quantity += line.Quantity
+			continue
+		}
+		index[line.Merch] = len(items)
+		items = append(items, domain.OrderItem{MerchName: line.Merch, Quantity: line.Quantity})
+	}
+
+	order, balance, err := o.orderRepository.PlaceOrder(ctx, userID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainAPI.OrderResponse{
+		OrderID:   order.ID,
+		TotalCost: order.TotalPrice,
+		Coins:     balance,
+		CreatedAt: order.CreatedAt.UTC(),
+	}, nil
+}
+
+func (o *orders) ListOrders(ctx context.Context, userID int) (*domainAPI.OrdersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.contextTimeout)
+	defer cancel()
+
+	userOrders, err := o.orderRepository.GetUserOrders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domainAPI.OrdersResponse{Orders: make([]domainAPI.OrderDetails, 0, len(userOrders))}
+	for i := range userOrders {
+		response.Orders = append(response.Orders, *orderDetails(&userOrders[i]))
+	}
+
+	return response, nil
+}
+
+func (o *orders) CancelOrder(ctx context.Context, userID, orderID int) (*domainAPI.OrderDetails, error) {
+	return o.transition(ctx, domain.OrderTransition{
+		OrderID: orderID,
+		OwnerID: userID,
+		ActorID: userID,
+		To:      domain.OrderStatusCancelled,
+	})
+}
+
+func (o *orders) ListPendingOrders(ctx context.Context, request *domainAPI.PendingOrdersRequest) (*domainAPI.OrdersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.contextTimeout)
+	defer cancel()
+
+	var afterID int
+	if request.Cursor != "" {
+		var err error
+		if afterID, err = domainAPI.DecodeCursor(request.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	pending, err := o.orderRepository.GetPendingOrders(ctx, afterID, request.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domainAPI.OrdersResponse{Orders: make([]domainAPI.OrderDetails, 0, len(pending))}
+	if len(pending) > request.Limit {
+		pending = pending[:request.Limit]
+		response.NextCursor = domainAPI.EncodeCursor(pending[len(pending)-1].ID)
+	}
+
+	for i := range pending {
+		details := orderDetails(&pending[i])
+		details.Owner = pending[i].OwnerName
+		response.Orders = append(response.Orders, *details)
+	}
+
+	return response, nil
+}
+
+func (o *orders) PickOrder(ctx context.Context, staffID, orderID int) (*domainAPI.OrderDetails, error) {
+	return o.transition(ctx, domain.OrderTransition{
+		OrderID: orderID,
+		ActorID: staffID,
+		To:      domain.OrderStatusPicked,
+	})
+}
+
+func (o *orders) DeliverOrder(ctx context.Context, staffID, orderID int, request *domainAPI.DeliverRequest) (*domainAPI.OrderDetails, error) {
+	return o.transition(ctx, domain.OrderTransition{
+		OrderID:  orderID,
+		ActorID:  staffID,
+		To:       domain.OrderStatusFulfilled,
+		Location: request.Location,
+	})
+}
+
+func (o *orders) RefundOrder(ctx context.Context, adminID, orderID int) (*domainAPI.OrderDetails, error) {
+	return o.transition(ctx, domain.OrderTransition{
+		OrderID: orderID,
+		ActorID: adminID,
+		To:      domain.OrderStatusRefunded,
+	})
+}
+
+func (o *orders) GetOrderEvents(ctx context.Context, orderID int) (*domainAPI.OrderEventsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.contextTimeout)
+	defer cancel()
+
+	events, err := o.orderRepository.GetOrderEvents(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domainAPI.OrderEventsResponse{Events: make([]domainAPI.OrderEvent, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, domainAPI.OrderEvent{
+			Status:    event.Status,
+			Actor:     event.ActorName,
+			Location:  event.Location,
+			CreatedAt: event.CreatedAt.UTC(),
+		})
+	}
+
+	return response, nil
+}
+
+func (o *orders) transition(ctx context.Context, change domain.OrderTransition) (*domainAPI.OrderDetails, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.contextTimeout)
+	defer cancel()
+
+	order, err := o.orderRepository.TransitionOrder(ctx, change)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderDetails(order), nil
+}
+
+func orderDetails(order *domain.Order) *domainAPI.OrderDetails {
+	details := &domainAPI.OrderDetails{
+		OrderID:          order.ID,
+		Status:           order.Status,
+		TotalCost:        order.TotalPrice,
+		DeliveryLocation: order.DeliveryLocation,
+		CreatedAt:        order.CreatedAt.UTC(),
+		Items:            make([]domainAPI.OrderItemDetails, 0, len(order.Items)),
+	}
+	for _, item := range order.Items {
+		details.Items = append(details.Items, domainAPI.OrderItemDetails{
+			Merch:    item.MerchName,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+		})
+	}
+	return details
+}
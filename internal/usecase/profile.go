@@ -2,7 +2,6 @@ package usecase
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/eslupmi101/avito_merch_store/internal/domain"
@@ -13,6 +12,7 @@ type profile struct {
 	orderRepository       domain.OrderRepository
 	transactionRepository domain.TransactionRepository
 	userRepository        domain.UserRepository
+	historyLimit          int
 	contextTimeout        time.Duration
 }
 
@@ -21,12 +21,14 @@ func NewProfile(
 	merchRepository domain.MerchRepository,
 	transactionRepository domain.TransactionRepository,
 	userRepository domain.UserRepository,
+	historyLimit int,
 	timeout time.Duration,
 ) domainAPI.ProfileUsecase {
 	return &profile{
 		orderRepository:       orderRepository,
 		transactionRepository: transactionRepository,
 		userRepository:        userRepository,
+		historyLimit:          historyLimit,
 		contextTimeout:        timeout,
 	}
 }
@@ -37,7 +39,11 @@ func (prf profile) GetProfile(ctx context.Context, userID int) (*domainAPI.Profi
 		return nil, err
 	}
 
-	transactions, err := prf.transactionRepository.GetUserTransactions(ctx, userID)
+	// The full history is served page by page by /api/transactions.
+	transactions, err := prf.transactionRepository.GetUserTransactions(ctx, domain.TransactionFilter{
+		UserID: userID,
+		Limit:  prf.historyLimit,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -53,13 +59,19 @@ func (prf profile) GetProfile(ctx context.Context, userID int) (*domainAPI.Profi
 	for _, t := range transactions {
 		if t.Recipient == userID {
 			received = append(received, domainAPI.Transaction{
-				FromUser: fmt.Sprintf("%d", t.Sender),
-				Amount:   t.Amount,
+				FromUser:  displayUsername(t.SenderUsername),
+				Amount:    t.Amount,
+				Message:   t.Message,
+				Reaction:  t.Reaction,
+				CreatedAt: t.CreatedAt.UTC(),
 			})
 		} else if t.Sender == userID {
 			sent = append(sent, domainAPI.Transaction{
-				ToUser: fmt.Sprintf("%d", t.Recipient),
-				Amount: t.Amount,
+				ToUser:    displayUsername(t.RecipientUsername),
+				Amount:    t.Amount,
+				Message:   t.Message,
+				Reaction:  t.Reaction,
+				CreatedAt: t.CreatedAt.UTC(),
 			})
 		}
 	}
@@ -73,8 +85,9 @@ func (prf profile) GetProfile(ctx context.Context, userID int) (*domainAPI.Profi
 	}
 
 	profileResponse := &domainAPI.ProfileResponse{
-		Coins:     user.Balance,
-		Inventory: inventory,
+		Coins:        user.Balance,
+		RegisteredAt: user.CreatedAt.UTC(),
+		Inventory:    inventory,
 		CoinHistory: domainAPI.CoinHistory{
 			Received: received,
 			Sent:     sent,
@@ -83,3 +96,11 @@ func (prf profile) GetProfile(ctx context.Context, userID int) (*domainAPI.Profi
 
 	return profileResponse, nil
 }
+
+// displayUsername substitutes a stable placeholder for counterparts whose account was deleted.
+func displayUsername(username string) string {
+	if username == "" {
+		return domain.DeletedUsername
+	}
+	return username
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+)
+
+type scheduledTransfers struct {
+	scheduledTransferRepository domain.ScheduledTransferRepository
+	batchSize                   int
+	contextTimeout              time.Duration
+}
+
+// NewScheduledTransfers creates the usecase behind /api/scheduledTransfers
+// and the scheduler; batchSize caps the transfers executed by one RunDue.
+func NewScheduledTransfers(
+	scheduledTransferRepository domain.ScheduledTransferRepository,
+	batchSize int,
+	timeout time.Duration,
+) domainAPI.ScheduledTransferUsecase {
+	return &scheduledTransfers{
+		scheduledTransferRepository: scheduledTransferRepository,
+		batchSize:                   batchSize,
+		contextTimeout:              timeout,
+	}
+}
+
+func (st *scheduledTransfers) Schedule(ctx context.Context, userID int, request *domainAPI.ScheduledTransferRequest) (*domainAPI.ScheduledTransferDetails, error) {
+	ctx, cancel := context.WithTimeout(ctx, st.contextTimeout)
+	defer cancel()
+
+	transfer, err := st.scheduledTransferRepository.Create(ctx, &domain.ScheduledTransfer{
+		SenderID:          userID,
+		RecipientUsername: request.ToUser,
+		Amount:            request.Amount,
+		Message:           request.Message,
+		Repeat:            request.Repeat,
+		StartAt:           request.RunAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	details := scheduledTransferDetails(*transfer)
+	return &details, nil
+}
+
+func (st *scheduledTransfers) List(ctx context.Context, userID int) (*domainAPI.ScheduledTransfersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, st.contextTimeout)
+	defer cancel()
+
+	transfers, err := st.scheduledTransferRepository.GetUserScheduledTransfers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domainAPI.ScheduledTransfersResponse{ScheduledTransfers: []domainAPI.ScheduledTransferDetails{}}
+	for _, transfer := range transfers {
+		response.ScheduledTransfers = append(response.ScheduledTransfers, scheduledTransferDetails(transfer))
+	}
+	return response, nil
+}
+
+func (st *scheduledTransfers) Cancel(ctx context.Context, userID, id int) (*domainAPI.ScheduledTransferDetails, error) {
+	ctx, cancel := context.WithTimeout(ctx, st.contextTimeout)
+	defer cancel()
+
+	transfer, err := st.scheduledTransferRepository.Cancel(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := scheduledTransferDetails(*transfer)
+	return &details, nil
+}
+
+func (st *scheduledTransfers) ListRuns(ctx context.Context, userID, id int) (*domainAPI.ScheduledTransferRunsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, st.contextTimeout)
+	defer cancel()
+
+	runs, err := st.scheduledTransferRepository.GetRuns(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domainAPI.ScheduledTransferRunsResponse{Runs: []domainAPI.ScheduledTransferRun{}}
+	for _, run := range runs {
+		response.Runs = append(response.Runs, domainAPI.ScheduledTransferRun{
+			ScheduledAt:   run.ScheduledAt.UTC(),
+			Status:        run.Status,
+			TransactionID: run.TransactionID,
+			Reason:        run.Reason,
+			CreatedAt:     run.CreatedAt.UTC(),
+		})
+	}
+	return response, nil
+}
+
+// RunDue executes due transfers one transaction at a time, each with its own
+// timeout, until none is left or the batch is done.
+func (st *scheduledTransfers) RunDue(ctx context.Context, now time.Time) (int, error) {
+	executed := 0
+	for executed < st.batchSize {
+		ran, err := st.runOne(ctx, now)
+		if err != nil || !ran {
+			return executed, err
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+func (st *scheduledTransfers) runOne(ctx context.Context, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, st.contextTimeout)
+	defer cancel()
+
+	_, ran, err := st.scheduledTransferRepository.RunDue(ctx, now)
+	return ran, err
+}
+
+func scheduledTransferDetails(transfer domain.ScheduledTransfer) domainAPI.ScheduledTransferDetails {
+	return domainAPI.ScheduledTransferDetails{
+		ID:        transfer.ID,
+		ToUser:    displayUsername(transfer.RecipientUsername),
+		Amount:    transfer.Amount,
+		Message:   transfer.Message,
+		Repeat:    transfer.Repeat,
+		Status:    transfer.Status,
+		NextRunAt: transfer.NextRunAt.UTC(),
+		CreatedAt: transfer.CreatedAt.UTC(),
+	}
+}
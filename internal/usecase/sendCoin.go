@@ -20,9 +20,49 @@ func NewCoinSender(transactionRepository domain.TransactionRepository, timeout t
 	}
 }
 
-func (cs *coinSender) SendCoinToUser(ctx context.Context, userID int, ToUser string, amount int) error {
+func (cs *coinSender) SendCoinToUser(ctx context.Context, userID int, ToUser string, amount int, message string) error {
 	ctx, cancel := context.WithTimeout(ctx, cs.contextTimeout)
 	defer cancel()
 
-	return cs.transactionRepository.SendCoinToUser(ctx, userID, ToUser, amount)
+	return cs.transactionRepository.SendCoinToUser(ctx, userID, ToUser, amount, message)
+}
+
+func (cs *coinSender) SendCoinsBulk(ctx context.Context, userID int, request *domainAPI.BulkSendRequest) (*domainAPI.BulkSendResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.contextTimeout)
+	defer cancel()
+
+	transfers := make([]domain.BulkTransfer, len(request.Transfers))
+	for i, transfer := range request.Transfers {
+		transfers[i] = domain.BulkTransfer{
+			ToUser:  transfer.ToUser,
+			Amount:  transfer.Amount,
+			Message: transfer.Message,
+		}
+	}
+
+	results, balance, err := cs.transactionRepository.SendCoinsBulk(ctx, userID, transfers)
+
+	response := &domainAPI.BulkSendResponse{Results: []domainAPI.BulkTransferResult{}}
+	if err == nil {
+		response.Coins = &balance
+	}
+	for _, result := range results {
+		entry := domainAPI.BulkTransferResult{
+			ToUser:        result.ToUser,
+			Amount:        result.Amount,
+			Status:        domainAPI.BulkTransferSent,
+			TransactionID: result.TransactionID,
+		}
+		switch {
+		case result.Err != nil:
+			entry.Status = domainAPI.BulkTransferFailed
+			entry.Error = result.Err.Error()
+		case err != nil:
+			entry.Status = domainAPI.BulkTransferNotSent
+		}
+		response.Total += result.Amount
+		response.Results = append(response.Results, entry)
+	}
+
+	return response, err
 }
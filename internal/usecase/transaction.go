@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+)
+
+type transactions struct {
+	transactionRepository domain.TransactionRepository
+	contextTimeout        time.Duration
+}
+
+func NewTransactions(transactionRepository domain.TransactionRepository, timeout time.Duration) domainAPI.TransactionUsecase {
+	return &transactions{
+		transactionRepository: transactionRepository,
+		contextTimeout:        timeout,
+	}
+}
+
+// ListTransactions returns a page of the user's transfers, newest first.
+// One extra row is fetched to tell whether another page follows.
+func (tr *transactions) ListTransactions(ctx context.Context, userID int, request *domainAPI.TransactionsRequest) (*domainAPI.TransactionsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, tr.contextTimeout)
+	defer cancel()
+
+	filter := domain.TransactionFilter{
+		UserID:       userID,
+		Direction:    request.Direction,
+		Counterparty: request.Counterparty,
+		From:         request.From,
+		To:           request.To,
+		Limit:        request.Limit + 1,
+	}
+	if request.Cursor != "" {
+		beforeID, err := domainAPI.DecodeCursor(request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.BeforeID = beforeID
+	}
+
+	page, err := tr.transactionRepository.GetUserTransactions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domainAPI.TransactionsResponse{Transactions: []domainAPI.TransactionEntry{}}
+	if len(page) > request.Limit {
+		page = page[:request.Limit]
+		response.NextCursor = domainAPI.EncodeCursor(page[len(page)-1].ID)
+	}
+
+	for _, t := range page {
+		entry := domainAPI.TransactionEntry{
+			ID:        t.ID,
+			Amount:    t.Amount,
+			Message:   t.Message,
+			Reaction:  t.Reaction,
+			CreatedAt: t.CreatedAt.UTC(),
+		}
+		if t.Sender == userID {
+			entry.Direction = domain.TransactionDirectionSent
+			entry.Counterparty = displayUsername(t.RecipientUsername)
+		} else {
+			entry.Direction = domain.TransactionDirectionReceived
+			entry.Counterparty = displayUsername(t.SenderUsername)
+		}
+		response.Transactions = append(response.Transactions, entry)
+	}
+
+	return response, nil
+}
+
+// React lets only the recipient react; the transfer is not found for anyone else.
+func (tr *transactions) React(ctx context.Context, userID, transactionID int, reaction string) error {
+	ctx, cancel := context.WithTimeout(ctx, tr.contextTimeout)
+	defer cancel()
+
+	return tr.transactionRepository.React(ctx, userID, transactionID, reaction)
+}
@@ -1,5 +1,24 @@
 package utility
 
-func JsonError(message string) string {
-	return `{"error": "` + message + `"}`
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// ErrorResponse is the envelope of every error returned by the API.
+type ErrorResponse struct {
+	Errors string `json:"errors"`
+}
+
+func WriteJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		slog.Error("Failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, ErrorResponse{Errors: message})
 }
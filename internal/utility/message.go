@@ -0,0 +1,22 @@
+package utility
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SanitizeMessage turns user-written text into a single line: line breaks
+// and tabs become spaces, other non-printable characters are dropped and
+// runs of spaces are collapsed.
+func SanitizeMessage(message string) string {
+	var sb strings.Builder
+	for _, r := range message {
+		switch {
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		case unicode.IsPrint(r):
+			sb.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
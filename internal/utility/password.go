@@ -0,0 +1,99 @@
+package utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordBackend computes and checks password hashes. PasswordHasher uses
+// bcrypt; tests substitute a backend to observe the calls.
+type PasswordBackend interface {
+	GenerateFromPassword(password []byte, cost int) ([]byte, error)
+	CompareHashAndPassword(hashedPassword, password []byte) error
+}
+
+type bcryptBackend struct{}
+
+func (bcryptBackend) GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, cost)
+}
+
+func (bcryptBackend) CompareHashAndPassword(hashedPassword, password []byte) error {
+	return bcrypt.CompareHashAndPassword(hashedPassword, password)
+}
+
+// PasswordHasher runs bcrypt on a bounded number of workers so that bursts
+// of logins queue up instead of starving the CPU.
+type PasswordHasher struct {
+	cost    int
+	workers chan struct{}
+	backend PasswordBackend
+}
+
+// NewPasswordHasher falls back to bcrypt.DefaultCost for an out of range cost
+// and to one worker per CPU when workers is not positive.
+func NewPasswordHasher(cost, workers int) *PasswordHasher {
+	return NewPasswordHasherWithBackend(cost, workers, bcryptBackend{})
+}
+
+func NewPasswordHasherWithBackend(cost, workers int, backend PasswordBackend) *PasswordHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &PasswordHasher{
+		cost:    cost,
+		workers: make(chan struct{}, workers),
+		backend: backend,
+	}
+}
+
+func (h *PasswordHasher) Hash(ctx context.Context, password string) (string, error) {
+	if err := h.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer h.release()
+
+	hashedPassword, err := h.backend.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate hashed password: %w", err)
+	}
+	return string(hashedPassword), nil
+}
+
+// Compare reports whether password matches hashedPassword.
+func (h *PasswordHasher) Compare(ctx context.Context, hashedPassword, password string) (bool, error) {
+	if err := h.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer h.release()
+
+	err := h.backend.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to compare password: %w", err)
+	}
+	return true, nil
+}
+
+func (h *PasswordHasher) acquire(ctx context.Context) error {
+	select {
+	case h.workers <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for password hasher: %w", ctx.Err())
+	}
+}
+
+func (h *PasswordHasher) release() {
+	<-h.workers
+}
@@ -0,0 +1,236 @@
+package utility
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultKeyID identifies the HMAC key derived from SECRET_KEY.
+const DefaultKeyID = "default"
+
+// SigningKey is a key the signer trusts. Keys past VerifyUntil are ignored;
+// a zero VerifyUntil means the key never expires.
+type SigningKey struct {
+	ID          string
+	Method      jwt.SigningMethod
+	VerifyUntil time.Time
+	signKey     interface{}
+	verifyKey   interface{}
+	public      crypto.PublicKey
+}
+
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// ParsePrivateKey builds an RS256 or EdDSA key from a PEM encoded private key.
+func ParsePrivateKey(id, algorithm string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data found", id)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if rsaErr != nil {
+			return nil, fmt.Errorf("key %s: failed to parse private key: %w", id, err)
+		}
+		privateKey = rsaKey
+	}
+
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		rsaKey, ok := privateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("key %s: %s requires an RSA private key", id, algorithm)
+		}
+		return &SigningKey{
+			ID:        id,
+			Method:    jwt.SigningMethodRS256,
+			signKey:   rsaKey,
+			verifyKey: &rsaKey.PublicKey,
+			public:    &rsaKey.PublicKey,
+		}, nil
+
+	case jwt.SigningMethodEdDSA.Alg():
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("key %s: %s requires an Ed25519 private key", id, algorithm)
+		}
+		publicKey := edKey.Public().(ed25519.PublicKey)
+		return &SigningKey{
+			ID:        id,
+			Method:    jwt.SigningMethodEdDSA,
+			signKey:   edKey,
+			verifyKey: publicKey,
+			public:    publicKey,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("key %s: unsupported algorithm %q", id, algorithm)
+	}
+}
+
+func (k *SigningKey) expired(now time.Time) bool {
+	return !k.VerifyUntil.IsZero() && now.After(k.VerifyUntil)
+}
+
+func (k *SigningKey) jwk() (domain.JWK, bool) {
+	jwk := domain.JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		// Symmetric keys are never published.
+		return domain.JWK{}, false
+	}
+
+	return jwk, true
+}
+
+type tokenSigner struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewTokenSigner signs with active and additionally verifies tokens signed
+// by the retired keys until they expire.
+func NewTokenSigner(active *SigningKey, retired ...*SigningKey) domain.TokenSigner {
+	keys := map[string]*SigningKey{active.ID: active}
+	for _, key := range retired {
+		keys[key.ID] = key
+	}
+
+	return &tokenSigner{active: active, keys: keys}
+}
+
+// LoadTokenSigner builds the signer from the configured keys, falling back
+// to an HS256 key derived from secretKey when no keys are configured.
+func LoadTokenSigner(keys []config.SigningKey, secretKey string) (domain.TokenSigner, error) {
+	if len(keys) == 0 {
+		if secretKey == "" {
+			return nil, errors.New("neither signing keys nor SECRET_KEY are configured")
+		}
+		return NewTokenSigner(NewHMACKey(DefaultKeyID, []byte(secretKey))), nil
+	}
+
+	var active *SigningKey
+	var retired []*SigningKey
+	seen := make(map[string]bool)
+
+	for _, keyCfg := range keys {
+		if keyCfg.ID == "" {
+			return nil, errors.New("signing key without kid")
+		}
+		if seen[keyCfg.ID] {
+			return nil, fmt.Errorf("duplicate signing key %s", keyCfg.ID)
+		}
+		seen[keyCfg.ID] = true
+
+		pemBytes, err := os.ReadFile(keyCfg.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keyCfg.ID, err)
+		}
+
+		key, err := ParsePrivateKey(keyCfg.ID, keyCfg.Algorithm, pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		key.VerifyUntil = keyCfg.VerifyUntil
+
+		if !keyCfg.Active {
+			retired = append(retired, key)
+			continue
+		}
+		if active != nil {
+			return nil, fmt.Errorf("signing keys %s and %s are both active", active.ID, key.ID)
+		}
+		if key.expired(time.Now()) {
+			return nil, fmt.Errorf("active signing key %s has expired", key.ID)
+		}
+		active = key
+	}
+
+	if active == nil {
+		return nil, errors.New("no active signing key")
+	}
+
+	return NewTokenSigner(active, retired...), nil
+}
+
+func (s *tokenSigner) Sign(claims *domain.TokenClaims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.signKey)
+}
+
+func (s *tokenSigner) Verify(requestToken string) (*domain.TokenClaims, error) {
+	claims := &domain.TokenClaims{}
+
+	token, err := jwt.ParseWithClaims(requestToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok || key.expired(time.Now()) {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return key.verifyKey, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %w", err)
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+func (s *tokenSigner) PublicKeys() domain.JWKS {
+	jwks := domain.JWKS{Keys: []domain.JWK{}}
+	now := time.Now()
+
+	for _, key := range s.keys {
+		if key.expired(now) {
+			continue
+		}
+		if jwk, ok := key.jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+
+	return jwks
+}
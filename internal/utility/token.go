@@ -1,6 +1,10 @@
 package utility
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -8,58 +12,45 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func CreateToken(userID int, secretKey string) (string, error) {
-	claims := &domain.TokenClaims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt: jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
-}
+const refreshTokenBytes = 32
 
-func parseToken(requestToken string, secret string) (*jwt.Token, *domain.TokenClaims, error) {
-	claims := &domain.TokenClaims{}
+// CreateToken issues an access token for principal; its TokenID is the
+// refresh session the token is bound to.
+func CreateToken(signer domain.TokenSigner, principal *domain.Principal, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := domain.NewTokenClaims(principal)
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
 
-	token, err := jwt.ParseWithClaims(requestToken, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(secret), nil
-	})
-
-	if err != nil {
-		return nil, nil, fmt.Errorf("error parsing token: %v", err)
-	}
-
-	return token, claims, nil
+	return signer.Sign(claims)
 }
 
-func IsAuthorized(requestToken string, secret string) (bool, error) {
-	token, _, err := parseToken(requestToken, secret)
-	if err != nil {
-		return false, err
+// CreateRefreshToken returns an opaque refresh token and the hash under which it is stored.
+func CreateRefreshToken() (string, string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	if !token.Valid {
-		return false, fmt.Errorf("invalid token")
-	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
 
-	return true, nil
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func ExtractIDFromToken(requestToken string, secret string) (int, error) {
-	token, claims, err := parseToken(requestToken, secret)
+// ParseToken validates the signature and expiry of requestToken and returns its claims.
+func ParseToken(signer domain.TokenSigner, requestToken string) (*domain.TokenClaims, error) {
+	claims, err := signer.Verify(requestToken)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+	if claims.ID == "" {
+		return nil, fmt.Errorf("token is not bound to a session")
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 }
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAdminRouter() *chi.Mux {
+	adminController := &controller.Admin{
+		AdminUsecase: usecase.NewAdmin(
+			repository.NewUserRepository(Db),
+			repository.NewSessionRepository(Db),
+			repository.NewLedgerRepository(Db),
+			2*time.Second,
+		),
+		Cfg: &config.Config{SecretKey: SecretKey},
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth, authTokenMiddleware.RequireRole(domain.RoleAdmin))
+	router.Put("/api/admin/users/{username}/roles", adminController.SetUserRoles)
+	router.Post("/api/admin/users/{username}/coins/grant", adminController.GrantCoins)
+	router.Post("/api/admin/users/{username}/coins/deduct", adminController.DeductCoins)
+	return router
+}
+
+func putRoles(t *testing.T, router *chi.Mux, token, username, body string) *httptest.ResponseRecorder {
+	return DoRequest(t, router, http.MethodPut, "/api/admin/users/"+username+"/roles", token, body)
+}
+
+func TestSetUserRolesSuccess(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	adminID := InsertUser(t, "admin", "password", 0)
+	SetRoles(t, adminID, domain.RoleUser, domain.RoleAdmin)
+	managerID := InsertUser(t, "manager", "password", 0)
+	managerToken := CreateToken(t, managerID)
+
+	rr := putRoles(t, newAdminRouter(), CreateToken(t, adminID), "manager", `{"roles":["store-manager"]}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+
+	var response domainAPI.UserRolesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.Equal(t, "manager", response.Username)
+	assert.Equal(t, []string{domain.RoleUser, domain.RoleStoreManager}, response.Roles, "User role should always be kept")
+
+	active, err := repository.NewSessionRepository(Db).IsActive(context.Background(), tokenID(t, managerToken))
+	if err != nil {
+		t.Fatalf("Failed to check session: %v", err)
+	}
+	assert.False(t, active, "Sessions carrying the old roles should be revoked")
+}
+
+func TestSetUserRolesForbidden(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 0)
+	InsertUser(t, "target", "password", 0)
+
+	rr := putRoles(t, newAdminRouter(), CreateToken(t, userID), "target", `{"roles":["admin"]}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Expected status 403 for a non-admin")
+
+	var roles []string
+	err := Db.Connection.QueryRow(context.Background(), "SELECT roles FROM users WHERE username = 'target'").Scan(&roles)
+	if err != nil {
+		t.Fatalf("Failed to query roles: %v", err)
+	}
+	assert.Equal(t, []string{domain.RoleUser}, roles, "Roles should remain unchanged")
+}
+
+func TestSetUserRolesInvalidRole(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	adminID := InsertUser(t, "admin", "password", 0)
+	SetRoles(t, adminID, domain.RoleUser, domain.RoleAdmin)
+	InsertUser(t, "target", "password", 0)
+
+	rr := putRoles(t, newAdminRouter(), CreateToken(t, adminID), "target", `{"roles":["superuser"]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for an unknown role")
+}
+
+func TestSetUserRolesUserNotFound(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	adminID := InsertUser(t, "admin", "password", 0)
+	SetRoles(t, adminID, domain.RoleUser, domain.RoleAdmin)
+
+	rr := putRoles(t, newAdminRouter(), CreateToken(t, adminID), "nobody", `{"roles":["admin"]}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status 404 for an unknown user")
+}
+
+func postCoins(t *testing.T, router *chi.Mux, token, path, body string) *httptest.ResponseRecorder {
+	return DoRequest(t, router, http.MethodPost, path, token, body)
+}
+
+func TestAdminAdjustCoins(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	adminID := InsertUser(t, "admin", "password", 0)
+	SetRoles(t, adminID, domain.RoleUser, domain.RoleAdmin)
+	adminToken := CreateToken(t, adminID)
+	registerUser(t, "alice")
+	router := newAdminRouter()
+
+	rr := postCoins(t, router, adminToken, "/api/admin/users/alice/coins/grant", `{"amount":500,"reason":" Hackathon prize "}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for a grant")
+	var response domainAPI.BalanceResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.Equal(t, domainAPI.BalanceResponse{Username: "alice", Coins: StartingBalance + 500}, response)
+
+	rr = postCoins(t, router, adminToken, "/api/admin/users/alice/coins/deduct", `{"amount":200,"reason":"Duplicate grant"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for a deduction")
+
+	rr = postCoins(t, router, adminToken, "/api/admin/users/alice/coins/deduct", fmt.Sprintf(`{"amount":%d,"reason":"Too much"}`, StartingBalance+301))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 when deducting more than the balance")
+
+	var balance int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT balance FROM users WHERE username = 'alice'").Scan(&balance)
+	if err != nil {
+		t.Fatalf("Failed to query balance: %v", err)
+	}
+	assert.Equal(t, StartingBalance+300, balance)
+
+	var actor int
+	var reason string
+	err = Db.Connection.QueryRow(context.Background(),
+		"SELECT actor, reason FROM ledger_entries WHERE kind = 'adjustment' ORDER BY id LIMIT 1").Scan(&actor, &reason)
+	if err != nil {
+		t.Fatalf("Failed to query ledger entry: %v", err)
+	}
+	assert.Equal(t, adminID, actor)
+	assert.Equal(t, "Hackathon prize", reason, "Reason should be trimmed")
+	assert.True(t, reconcile(t).Consistent(), "Adjustments should be recorded in the ledger")
+}
+
+func TestAdminAdjustCoinsValidation(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	adminID := InsertUser(t, "admin", "password", 0)
+	SetRoles(t, adminID, domain.RoleUser, domain.RoleAdmin)
+	adminToken := CreateToken(t, adminID)
+	userID := InsertUser(t, "user", "password", 0)
+	router := newAdminRouter()
+
+	for _, body := range []string{
+		`{"amount":0,"reason":"Nothing"}`,
+		`{"amount":-5,"reason":"Negative"}`,
+		`{"amount":5,"reason":"   "}`,
+		`{"amount":5,"reason":"Bell\u0007"}`,
+		`{"amount":5,"reason":"` + strings.Repeat("a", 256) + `"}`,
+	} {
+		rr := postCoins(t, router, adminToken, "/api/admin/users/user/coins/grant", body)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for %s", body)
+	}
+
+	rr := postCoins(t, router, adminToken, "/api/admin/users/nobody/coins/grant", `{"amount":5,"reason":"Prize"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status 404 for an unknown user")
+
+	rr = postCoins(t, router, CreateToken(t, userID), "/api/admin/users/user/coins/grant", `{"amount":5,"reason":"Prize"}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Expected status 403 for a non-admin")
+}
+
+func tokenID(t *testing.T, token string) string {
+	claims, err := TokenSigner.Verify(token)
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	return claims.ID
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowanceCreditsActiveUsersOncePerMonth(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	registerUser(t, "active")
+	inactiveID := registerUser(t, "inactive")
+	InsertUser(t, "never", "password", 0)
+
+	_, err := Db.Connection.Exec(context.Background(),
+		"UPDATE users SET last_login_at = NOW() - INTERVAL '60 days' WHERE id = $1", inactiveID)
+	if err != nil {
+		t.Fatalf("Failed to update last login: %v", err)
+	}
+
+	allowance := usecase.NewAllowance(repository.NewAllowanceRepository(Db), 100, 30*24*time.Hour, 2*time.Second)
+	now := time.Now()
+
+	run, err := allowance.CreditDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Failed to credit allowance: %v", err)
+	}
+	if assert.NotNil(t, run, "The first run of a month should credit users") {
+		assert.Equal(t, 1, run.CreditedUsers)
+	}
+
+	run, err = allowance.CreditDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Failed to credit allowance: %v", err)
+	}
+	assert.Nil(t, run, "A month should be paid only once")
+
+	balances := map[string]int{}
+	rows, err := Db.Connection.Query(context.Background(), "SELECT username, balance FROM users")
+	if err != nil {
+		t.Fatalf("Failed to query balances: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var username string
+		var balance int
+		if err := rows.Scan(&username, &balance); err != nil {
+			t.Fatalf("Failed to scan balance: %v", err)
+		}
+		balances[username] = balance
+	}
+	assert.Equal(t, map[string]int{
+		"active":   StartingBalance + 100,
+		"inactive": StartingBalance,
+		"never":    0,
+	}, balances)
+	assert.True(t, reconcile(t).Consistent(), "Allowance should be recorded in the ledger")
+}
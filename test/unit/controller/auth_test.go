@@ -8,14 +8,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
 	"github.com/eslupmi101/avito_merch_store/internal/repository"
 	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
@@ -38,7 +42,16 @@ func TestAuthSuccess(t *testing.T) {
 	}
 
 	userRepo := repository.NewUserRepository(Db)
-	authUsecase := usecase.NewAuth(userRepo, 2*time.Second)
+	authUsecase := usecase.NewAuth(
+		userRepo,
+		repository.NewSessionRepository(Db),
+		PasswordHasher,
+		TokenSigner,
+		time.Hour,
+		24*time.Hour,
+		StartingBalance,
+		2*time.Second,
+	)
 	cfg := &config.Config{SecretKey: "testsecret"}
 	authController := &controller.Auth{
 		AuthUsecase: authUsecase,
@@ -91,7 +104,16 @@ func TestAuthWithRegistrationSuccess(t *testing.T) {
 
 	// Создаем необходимые зависимости
 	userRepo := repository.NewUserRepository(Db)
-	authUsecase := usecase.NewAuth(userRepo, 2*time.Second)
+	authUsecase := usecase.NewAuth(
+		userRepo,
+		repository.NewSessionRepository(Db),
+		PasswordHasher,
+		TokenSigner,
+		time.Hour,
+		24*time.Hour,
+		StartingBalance,
+		2*time.Second,
+	)
 	cfg := &config.Config{SecretKey: "testsecret"}
 	authController := &controller.Auth{
 		AuthUsecase: authUsecase,
@@ -154,7 +176,16 @@ func TestAuthWithAuthFailure(t *testing.T) {
 
 	// Создаем зависимости для контроллера
 	userRepo := repository.NewUserRepository(Db)
-	authUsecase := usecase.NewAuth(userRepo, 2*time.Second)
+	authUsecase := usecase.NewAuth(
+		userRepo,
+		repository.NewSessionRepository(Db),
+		PasswordHasher,
+		TokenSigner,
+		time.Hour,
+		24*time.Hour,
+		StartingBalance,
+		2*time.Second,
+	)
 	cfg := &config.Config{SecretKey: "testsecret"}
 	authController := &controller.Auth{
 		AuthUsecase: authUsecase,
@@ -201,7 +232,16 @@ func TestAuthWithAuthFailure(t *testing.T) {
 func TestAuthInvalidJsonFailure(t *testing.T) {
 	// Создаем контроллер и используем репозитории/кейсы
 	userRepo := repository.NewUserRepository(Db)
-	authUsecase := usecase.NewAuth(userRepo, 2*time.Second)
+	authUsecase := usecase.NewAuth(
+		userRepo,
+		repository.NewSessionRepository(Db),
+		PasswordHasher,
+		TokenSigner,
+		time.Hour,
+		24*time.Hour,
+		StartingBalance,
+		2*time.Second,
+	)
 	cfg := &config.Config{SecretKey: "testsecret"}
 	authController := &controller.Auth{
 		AuthUsecase: authUsecase,
@@ -304,9 +344,274 @@ func TestAuthInvalidJsonFailure(t *testing.T) {
 				t.Fatalf("Failed to parse response body: %v", err)
 			}
 
-			if response["error"] != tt.expectedError {
-				t.Errorf("Expected error message %v, got %v", tt.expectedError, response["error"])
+			if response["errors"] != tt.expectedError {
+				t.Errorf("Expected error message %v, got %v", tt.expectedError, response["errors"])
 			}
 		})
 	}
 }
+
+// ------------------ Тесты Login/Register ------------------
+
+func newAuthRouter(cfg *config.Config) *chi.Mux {
+	return newAuthRouterWithHasher(cfg, PasswordHasher)
+}
+
+func newAuthRouterWithHasher(cfg *config.Config, passwordHasher *utility.PasswordHasher) *chi.Mux {
+	userRepo := repository.NewUserRepository(Db)
+	authController := &controller.Auth{
+		AuthUsecase: usecase.NewAuth(
+			userRepo,
+			repository.NewSessionRepository(Db),
+			passwordHasher,
+			TokenSigner,
+			time.Hour,
+			24*time.Hour,
+			StartingBalance,
+			2*time.Second,
+		),
+		Cfg: cfg,
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware())
+	router.Post("/api/auth", authController.Authentication)
+	router.Post("/api/auth/login", authController.Login)
+	router.Post("/api/auth/register", authController.Register)
+	router.Post("/api/auth/refresh", authController.Refresh)
+	router.With(authTokenMiddleware.RequireAuth).Post("/api/auth/logout", authController.Logout)
+	return router
+}
+
+func postAuth(t *testing.T, router *chi.Mux, path, username, password string) *httptest.ResponseRecorder {
+	requestBody, _ := json.Marshal(domainAPI.AuthRequest{Username: username, Password: password})
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatalf("Не удалось создать HTTP-запрос: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func countUsers(t *testing.T, username string) int {
+	var userCount int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT COUNT(*) FROM users WHERE username = $1", username).Scan(&userCount)
+	if err != nil {
+		t.Fatalf("Ошибка при проверке наличия пользователя: %v", err)
+	}
+	return userCount
+}
+
+func TestRegisterSuccess(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newAuthRouter(&config.Config{SecretKey: "testsecret"})
+	rr := postAuth(t, router, "/api/auth/register", "newuser", "newpassword")
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Ожидался статус-код 201 Created")
+
+	var authResponse domainAPI.AuthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &authResponse); err != nil {
+		t.Fatalf("Не удалось распарсить тело ответа: %v", err)
+	}
+	assert.NotEmpty(t, authResponse.Token, "Токен не должен быть пустым")
+	assert.Equal(t, 1, countUsers(t, "newuser"), "Пользователь не был создан в базе данных")
+}
+
+func TestRegisterExistingUserFailure(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	InsertUser(t, "testuser", "testpassword", 100)
+
+	router := newAuthRouter(&config.Config{SecretKey: "testsecret"})
+	rr := postAuth(t, router, "/api/auth/register", "testuser", "testpassword")
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Ожидался статус-код 409 Conflict")
+}
+
+func TestLoginSuccess(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	InsertUser(t, "testuser", "testpassword", 100)
+
+	router := newAuthRouter(&config.Config{SecretKey: "testsecret"})
+	rr := postAuth(t, router, "/api/auth/login", "testuser", "testpassword")
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Ожидался статус-код 200 OK")
+}
+
+func TestLoginUnknownUserFailure(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newAuthRouter(&config.Config{SecretKey: "testsecret"})
+	rr := postAuth(t, router, "/api/auth/login", "typouser", "testpassword")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+	assert.Equal(t, 0, countUsers(t, "typouser"), "Пользователь не должен создаваться при входе")
+}
+
+// countingBackend hashes with bcrypt and counts the calls.
+type countingBackend struct {
+	generated atomic.Int32
+	compared  atomic.Int32
+}
+
+func (b *countingBackend) GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	b.generated.Add(1)
+	return bcrypt.GenerateFromPassword(password, cost)
+}
+
+func (b *countingBackend) CompareHashAndPassword(hashedPassword, password []byte) error {
+	b.compared.Add(1)
+	return bcrypt.CompareHashAndPassword(hashedPassword, password)
+}
+
+func TestAuthExistingUserDoesNotHash(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	InsertUser(t, "testuser", "testpassword", 100)
+	backend := &countingBackend{}
+	router := newAuthRouterWithHasher(&config.Config{SecretKey: "testsecret"},
+		utility.NewPasswordHasherWithBackend(bcrypt.MinCost, 0, backend))
+
+	rr := postAuth(t, router, "/api/auth", "testuser", "testpassword")
+	assert.Equal(t, http.StatusOK, rr.Code, "Ожидался статус-код 200 OK")
+	assert.Equal(t, int32(0), backend.generated.Load(), "Вход существующего пользователя не должен хешировать пароль")
+	assert.Equal(t, int32(1), backend.compared.Load(), "Пароль должен сравниваться один раз")
+
+	rr = postAuth(t, router, "/api/auth", "newuser", "newpassword")
+	assert.Equal(t, http.StatusOK, rr.Code, "Ожидался статус-код 200 OK")
+	assert.Equal(t, int32(1), backend.generated.Load(), "Пароль хешируется только при регистрации")
+}
+
+func TestAuthLoginModeDoesNotRegister(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	cfg := &config.Config{SecretKey: "testsecret", Auth: config.Auth{Mode: config.AuthModeLogin}}
+	router := newAuthRouter(cfg)
+	rr := postAuth(t, router, "/api/auth", "typouser", "testpassword")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+	assert.Equal(t, 0, countUsers(t, "typouser"), "Пользователь не должен создаваться в режиме login")
+}
+
+// ------------------ Тесты Refresh/Logout ------------------
+
+func decodeAuthResponse(t *testing.T, rr *httptest.ResponseRecorder) domainAPI.AuthResponse {
+	var authResponse domainAPI.AuthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &authResponse); err != nil {
+		t.Fatalf("Не удалось распарсить тело ответа: %v", err)
+	}
+	return authResponse
+}
+
+func postRefresh(t *testing.T, router *chi.Mux, refreshToken string) *httptest.ResponseRecorder {
+	requestBody, _ := json.Marshal(domainAPI.RefreshRequest{RefreshToken: refreshToken})
+	req, err := http.NewRequest(http.MethodPost, "/api/auth/refresh", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatalf("Не удалось создать HTTP-запрос: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func postLogout(t *testing.T, router *chi.Mux, token string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+	if err != nil {
+		t.Fatalf("Не удалось создать HTTP-запрос: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRefreshTokenSuccess(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newAuthRouter(&config.Config{SecretKey: SecretKey})
+	authResponse := decodeAuthResponse(t, postAuth(t, router, "/api/auth/register", "newuser", "newpassword"))
+	assert.NotEmpty(t, authResponse.RefreshToken, "Refresh-токен не должен быть пустым")
+
+	rr := postRefresh(t, router, authResponse.RefreshToken)
+	assert.Equal(t, http.StatusOK, rr.Code, "Ожидался статус-код 200 OK")
+
+	refreshed := decodeAuthResponse(t, rr)
+	assert.NotEmpty(t, refreshed.Token, "Токен не должен быть пустым")
+	assert.NotEqual(t, authResponse.RefreshToken, refreshed.RefreshToken, "Refresh-токен должен обновляться")
+
+	// Повторное использование refresh-токена запрещено
+	rr = postRefresh(t, router, authResponse.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+
+	// Access-токен отозванной сессии больше не принимается
+	rr = postLogout(t, router, authResponse.Token)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newAuthRouter(&config.Config{SecretKey: SecretKey})
+	authResponse := decodeAuthResponse(t, postAuth(t, router, "/api/auth/register", "newuser", "newpassword"))
+
+	rr := postLogout(t, router, authResponse.Token)
+	assert.Equal(t, http.StatusOK, rr.Code, "Ожидался статус-код 200 OK")
+
+	rr = postLogout(t, router, authResponse.Token)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+
+	rr = postRefresh(t, router, authResponse.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+}
+
+func TestExpiredTokenFailure(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "testuser", "testpassword", 100)
+	session, err := repository.NewSessionRepository(Db).Create(context.Background(), userID, "expired", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Не удалось создать сессию: %v", err)
+	}
+	token, err := utility.CreateToken(TokenSigner, &domain.Principal{UserID: userID, TokenID: session.ID}, -time.Minute)
+	if err != nil {
+		t.Fatalf("Не удалось создать токен: %v", err)
+	}
+
+	router := newAuthRouter(&config.Config{SecretKey: SecretKey})
+	rr := postLogout(t, router, token)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Ожидался статус-код 401 Unauthorized")
+}
+
+func TestTokenCarriesPrincipal(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newAuthRouter(&config.Config{SecretKey: SecretKey})
+	authResponse := decodeAuthResponse(t, postAuth(t, router, "/api/auth/register", "newuser", "newpassword"))
+
+	claims, err := utility.ParseToken(TokenSigner, authResponse.Token)
+	if err != nil {
+		t.Fatalf("Не удалось разобрать токен: %v", err)
+	}
+
+	principal := claims.Principal()
+	assert.NotZero(t, principal.UserID, "Токен должен содержать ID пользователя")
+	assert.Equal(t, "newuser", principal.Username, "Токен должен содержать имя пользователя")
+	assert.NotEmpty(t, principal.TokenID, "Токен должен быть привязан к сессии")
+}
@@ -12,7 +12,6 @@ import (
 	"github.com/eslupmi101/avito_merch_store/internal/config"
 	"github.com/eslupmi101/avito_merch_store/internal/repository"
 	"github.com/eslupmi101/avito_merch_store/internal/usecase"
-	"github.com/eslupmi101/avito_merch_store/internal/utility"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,13 +43,10 @@ func TestBuySuccess(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/buy/{merchName}", buyController.Buy)
 
-	token, err := utility.CreateToken(userID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, userID)
 
 	req, err := http.NewRequest(http.MethodGet, "/api/buy/t-shirt", nil)
 	if err != nil {
@@ -72,7 +68,8 @@ func TestBuySuccess(t *testing.T) {
 
 	var count int
 	err = Db.Connection.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM merch_orders WHERE owner = $1 AND merch = $2", userID, merchID).Scan(&count)
+		`SELECT COUNT(*) FROM merch_orders mo JOIN merch_order_items i ON i.order_id = mo.id
+		WHERE mo.owner = $1 AND i.merch = $2 AND i.quantity = 1 AND i.price = 80`, userID, merchID).Scan(&count)
 	if err != nil {
 		t.Fatalf("Failed to query merch_orders: %v", err)
 	}
@@ -95,13 +92,10 @@ func TestBuyFailed(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/buy/{merchName}", buyController.Buy)
 
-	token, err := utility.CreateToken(userID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, userID)
 
 	req, err := http.NewRequest(http.MethodGet, "/api/buy/cup", nil)
 	if err != nil {
@@ -146,13 +140,10 @@ func TestBuyFakeMerchFailed(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/buy/{merchName}", buyController.Buy)
 
-	token, err := utility.CreateToken(userID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, userID)
 
 	req, err := http.NewRequest(http.MethodGet, "/api/buy/"+fakeMerchName, nil)
 	if err != nil {
@@ -197,13 +188,10 @@ func TestBuyBadTokenFailed(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/buy/{merchName}", buyController.Buy)
 
-	token, err := utility.CreateToken(userID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, userID)
 	badToken := token + "invalid"
 
 	req, err := http.NewRequest(http.MethodGet, "/api/buy/book", nil)
@@ -248,7 +236,7 @@ func TestBuyWithoutTokenFailed(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/buy/{merchName}", buyController.Buy)
 
 	req, err := http.NewRequest(http.MethodGet, "/api/buy/pen", nil)
@@ -275,3 +263,104 @@ func TestBuyWithoutTokenFailed(t *testing.T) {
 	}
 	assert.Equal(t, 0, count, "No merch order should be created")
 }
+
+func TestBuyArchivedMerchFailed(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 500)
+	merchID := insertMerch(t, "cup", 20)
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE merch SET archived = TRUE WHERE id = $1", merchID)
+	if err != nil {
+		t.Fatalf("Failed to archive merch: %v", err)
+	}
+
+	or := repository.NewOrderRepository(Db)
+	buyUsecase := usecase.NewOrder(or, 2*time.Second)
+	cfg := &config.Config{SecretKey: "testsecret"}
+	buyController := &controller.Buy{
+		BuyUsecase: buyUsecase,
+		Cfg:        cfg,
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Get("/api/buy/{merchName}", buyController.Buy)
+
+	token := CreateToken(t, userID)
+
+	req, err := http.NewRequest(http.MethodGet, "/api/buy/cup", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for archived merch")
+
+	var balance int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if err != nil {
+		t.Fatalf("Failed to query user balance: %v", err)
+	}
+	assert.Equal(t, 500, balance, "User balance should remain unchanged")
+}
+
+func TestBuyOutOfStockFailed(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 500)
+	merchID := insertMerch(t, "cup", 20)
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE merch SET stock = 1 WHERE id = $1", merchID)
+	if err != nil {
+		t.Fatalf("Failed to set merch stock: %v", err)
+	}
+
+	or := repository.NewOrderRepository(Db)
+	buyUsecase := usecase.NewOrder(or, 2*time.Second)
+	cfg := &config.Config{SecretKey: "testsecret"}
+	buyController := &controller.Buy{
+		BuyUsecase: buyUsecase,
+		Cfg:        cfg,
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Get("/api/buy/{merchName}", buyController.Buy)
+
+	token := CreateToken(t, userID)
+
+	buy := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/api/buy/cup", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, buy().Code, "Expected status 200 OK while in stock")
+
+	rr := buy()
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for sold-out merch")
+	assert.Contains(t, rr.Body.String(), "Merch is out of stock")
+
+	var balance, stock int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if err != nil {
+		t.Fatalf("Failed to query user balance: %v", err)
+	}
+	assert.Equal(t, 480, balance, "Only the first purchase should be charged")
+
+	err = Db.Connection.QueryRow(context.Background(), "SELECT stock FROM merch WHERE id = $1", merchID).Scan(&stock)
+	if err != nil {
+		t.Fatalf("Failed to query merch stock: %v", err)
+	}
+	assert.Equal(t, 0, stock, "Stock should be decremented")
+}
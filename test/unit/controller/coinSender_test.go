@@ -2,18 +2,22 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/eslupmi101/avito_merch_store/api/controller"
 	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
 	"github.com/eslupmi101/avito_merch_store/internal/repository"
 	"github.com/eslupmi101/avito_merch_store/internal/usecase"
-	"github.com/eslupmi101/avito_merch_store/internal/utility"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,13 +39,10 @@ func TestSendCoinSuccess(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Post("/api/sendCoin", csController.CoinSender)
 
-	token, err := utility.CreateToken(senderID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, senderID)
 
 	// Prepare request
 	reqBody := `{"toUser":"receiver", "amount":100}`
@@ -71,6 +72,16 @@ func TestSendCoinSuccess(t *testing.T) {
 		t.Fatalf("Failed to query receiver balance: %v", err)
 	}
 	assert.Equal(t, 200, receiverBalance, "Receiver balance should be credited correctly")
+
+	// Check ledger entry
+	var sender, recipient, amount int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT sender, recipient, amount FROM transactions").Scan(&sender, &recipient, &amount)
+	if err != nil {
+		t.Fatalf("Failed to query transaction: %v", err)
+	}
+	assert.Equal(t, senderID, sender, "Transaction sender should be recorded")
+	assert.Equal(t, receiverID, recipient, "Transaction recipient should be recorded")
+	assert.Equal(t, 100, amount, "Transaction amount should be recorded")
 }
 
 func TestSendCoinInsufficientBalance(t *testing.T) {
@@ -90,13 +101,10 @@ func TestSendCoinInsufficientBalance(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Post("/api/sendCoin", csController.CoinSender)
 
-	token, err := utility.CreateToken(senderID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, senderID)
 
 	// Prepare request with amount greater than balance
 	reqBody := `{"toUser":"receiver", "amount":100}`
@@ -144,13 +152,10 @@ func TestSendCoinUserNotFound(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Post("/api/sendCoin", csController.CoinSender)
 
-	token, err := utility.CreateToken(senderID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, senderID)
 
 	// Prepare request with non-existent user
 	reqBody := `{"toUser":"nonexistentuser", "amount":100}`
@@ -165,6 +170,20 @@ func TestSendCoinUserNotFound(t *testing.T) {
 
 	// Assert failure due to user not found
 	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for non-existent user")
+
+	var senderBalance int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT balance FROM users WHERE id = $1", senderID).Scan(&senderBalance)
+	if err != nil {
+		t.Fatalf("Failed to query sender balance: %v", err)
+	}
+	assert.Equal(t, 500, senderBalance, "Sender balance should remain unchanged")
+
+	var count int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT COUNT(*) FROM transactions").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query transactions: %v", err)
+	}
+	assert.Equal(t, 0, count, "No transaction should be recorded")
 }
 
 func TestSendCoinInvalidToken(t *testing.T) {
@@ -182,7 +201,7 @@ func TestSendCoinInvalidToken(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Post("/api/sendCoin", csController.CoinSender)
 
 	// Prepare request with invalid token
@@ -215,7 +234,7 @@ func TestSendCoinMissingToken(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Post("/api/sendCoin", csController.CoinSender)
 
 	// Prepare request without token
@@ -248,13 +267,10 @@ func TestSendCoinZeroAmount(t *testing.T) {
 	}
 
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Post("/api/sendCoin", csController.CoinSender)
 
-	token, err := utility.CreateToken(senderID, cfg.SecretKey)
-	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
-	}
+	token := CreateToken(t, senderID)
 
 	// Prepare request with zero amount
 	reqBody := `{"toUser":"receiver", "amount":0}`
@@ -270,3 +286,340 @@ func TestSendCoinZeroAmount(t *testing.T) {
 	// Assert failure due to zero amount
 	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for zero amount")
 }
+
+func TestSendCoinToSelf(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	// Setup user
+	senderID := InsertUser(t, "sender", "password", 500)
+
+	cs := repository.NewTransactionRepository(Db)
+	csUsecase := usecase.NewCoinSender(cs, 2*time.Second)
+	cfg := &config.Config{SecretKey: "testsecret"}
+	csController := &controller.CoinSender{
+		CoinSenderUsecase: csUsecase,
+		Cfg:               cfg,
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Post("/api/sendCoin", csController.CoinSender)
+
+	token := CreateToken(t, senderID)
+
+	// Prepare request addressed to the sender
+	reqBody := `{"toUser":"sender", "amount":100}`
+	req, err := http.NewRequest(http.MethodPost, "/api/sendCoin", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// Assert failure due to self-transfer
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for self-transfer")
+
+	var senderBalance int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT balance FROM users WHERE id = $1", senderID).Scan(&senderBalance)
+	if err != nil {
+		t.Fatalf("Failed to query sender balance: %v", err)
+	}
+	assert.Equal(t, 500, senderBalance, "Sender balance should remain unchanged")
+}
+
+type sendResult struct {
+	code int
+	err  error
+}
+
+// sendCoin posts a transfer without *testing.T, so it can run in goroutines.
+func sendCoin(router *chi.Mux, token, body string) sendResult {
+	req, err := http.NewRequest(http.MethodPost, "/api/sendCoin", strings.NewReader(body))
+	if err != nil {
+		return sendResult{err: err}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return sendResult{code: rr.Code}
+}
+
+func TestSendCoinConcurrentOppositeTransfers(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	aliceID := InsertUser(t, "alice", "password", 10000)
+	bobID := InsertUser(t, "bob", "password", 10000)
+	aliceToken := CreateToken(t, aliceID)
+	bobToken := CreateToken(t, bobID)
+	router := newIdempotentRouter()
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	results := make(chan sendResult, 2*rounds)
+	for i := 0; i < rounds; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			results <- sendCoin(router, aliceToken, `{"toUser":"bob","amount":3}`)
+		}()
+		go func() {
+			defer wg.Done()
+			results <- sendCoin(router, bobToken, `{"toUser":"alice","amount":1}`)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for result := range results {
+		if assert.NoError(t, result.err) {
+			assert.Equal(t, http.StatusOK, result.code, "Every transfer should succeed without deadlocks")
+		}
+	}
+
+	// Each transfer must be applied exactly once on both sides.
+	assert.Equal(t, 10000-rounds*3+rounds*1, queryBalance(t, aliceID))
+	assert.Equal(t, 10000+rounds*3-rounds*1, queryBalance(t, bobID))
+
+	var count int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT COUNT(*) FROM transactions").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	assert.Equal(t, 2*rounds, count)
+}
+
+func TestSendCoinToUnknownUserLeavesBalance(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 500)
+	router := newIdempotentRouter()
+
+	rr := postWithKey(t, router, "/api/sendCoin", CreateToken(t, senderID), "", `{"toUser":"ghost","amount":600}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Unknown recipient should be reported before the balance check")
+	assert.Contains(t, rr.Body.String(), "toUser does not exist")
+	assert.Equal(t, 500, queryBalance(t, senderID))
+}
+
+func TestSendCoinWithMessage(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 500)
+	receiverID := InsertUser(t, "receiver", "password", 0)
+	router := newIdempotentRouter()
+
+	rr := postWithKey(t, router, "/api/sendCoin", CreateToken(t, senderID), "",
+		`{"toUser":"receiver","amount":10,"message":"  Thanks for\nthe\u0000 review!\t "}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for a transfer with a message")
+
+	_, response := getTransactions(t, newTransactionsRouter(), CreateToken(t, receiverID), url.Values{})
+	if assert.Len(t, response.Transactions, 1) {
+		assert.Equal(t, "Thanks for the review!", response.Transactions[0].Message, "Message should be sanitized")
+	}
+}
+
+func TestSendCoinMessageTooLong(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 500)
+	InsertUser(t, "receiver", "password", 0)
+	router := newIdempotentRouter()
+
+	body := `{"toUser":"receiver","amount":10,"message":"` + strings.Repeat("ж", 201) + `"}`
+	rr := postWithKey(t, router, "/api/sendCoin", CreateToken(t, senderID), "", body)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for a message over 200 characters")
+	assert.Equal(t, 500, queryBalance(t, senderID), "Balance should remain unchanged")
+}
+
+func postBulk(t *testing.T, token, body string) (*httptest.ResponseRecorder, domainAPI.BulkSendResponse) {
+	rr := postWithKey(t, newIdempotentRouter(), "/api/sendCoin/bulk", token, "", body)
+
+	var response domainAPI.BulkSendResponse
+	if strings.HasPrefix(rr.Body.String(), "{") {
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return rr, response
+}
+
+// leadToken grants the team lead role, which bulk transfers require.
+func leadToken(t *testing.T, userID int) string {
+	SetRoles(t, userID, domain.RoleUser, domain.RoleTeamLead)
+	return CreateToken(t, userID)
+}
+
+func countTransactions(t *testing.T) int {
+	var count int
+	if err := Db.Connection.QueryRow(context.Background(), "SELECT COUNT(*) FROM transactions").Scan(&count); err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	return count
+}
+
+func TestSendCoinBulkSuccess(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	leadID := registerUser(t, "lead")
+	aliceID := registerUser(t, "alice")
+	bobID := registerUser(t, "bob")
+
+	rr, response := postBulk(t, leadToken(t, leadID),
+		`{"message":"Great sprint!","transfers":[{"toUser":"alice","amount":30},{"toUser":"bob","amount":20,"message":"Thanks for the demo"}]}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for a bulk transfer")
+	if assert.NotNil(t, response.Coins) {
+		assert.Equal(t, StartingBalance-50, *response.Coins)
+	}
+	assert.Equal(t, 50, response.Total)
+	if assert.Len(t, response.Results, 2) {
+		for _, result := range response.Results {
+			assert.Equal(t, domainAPI.BulkTransferSent, result.Status)
+			assert.NotZero(t, result.TransactionID)
+		}
+	}
+
+	assert.Equal(t, StartingBalance+30, queryBalance(t, aliceID))
+	assert.Equal(t, StartingBalance+20, queryBalance(t, bobID))
+
+	_, history := getTransactions(t, newTransactionsRouter(), CreateToken(t, aliceID), url.Values{})
+	if assert.Len(t, history.Transactions, 1) {
+		assert.Equal(t, "Great sprint!", history.Transactions[0].Message, "Shared message should apply to entries without one")
+	}
+	assert.True(t, reconcile(t).Consistent(), "Bulk transfers should be recorded in the ledger")
+}
+
+func TestSendCoinBulkRequiresTeamLead(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "member", "password", 500)
+	InsertUser(t, "alice", "password", 0)
+
+	rr, _ := postBulk(t, CreateToken(t, userID), `{"transfers":[{"toUser":"alice","amount":30}]}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Expected status 403 without the team lead role")
+	assert.Equal(t, 500, queryBalance(t, userID))
+	assert.Equal(t, 0, countTransactions(t))
+}
+
+func TestSendCoinBulkUnknownRecipientSendsNothing(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	leadID := InsertUser(t, "lead", "password", 500)
+	aliceID := InsertUser(t, "alice", "password", 0)
+
+	rr, response := postBulk(t, leadToken(t, leadID),
+		`{"transfers":[{"toUser":"alice","amount":30},{"toUser":"ghost","amount":20}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for an unknown recipient")
+	assert.Equal(t, "toUser does not exist", response.Errors)
+	if assert.Len(t, response.Results, 2) {
+		assert.Equal(t, domainAPI.BulkTransferNotSent, response.Results[0].Status)
+		assert.Equal(t, domainAPI.BulkTransferFailed, response.Results[1].Status)
+		assert.Equal(t, domain.ErrRecipientNotFound.Error(), response.Results[1].Error)
+	}
+
+	assert.Equal(t, 500, queryBalance(t, leadID), "Balance should remain unchanged")
+	assert.Equal(t, 0, queryBalance(t, aliceID))
+	assert.Equal(t, 0, countTransactions(t))
+}
+
+func TestSendCoinBulkInsufficientFunds(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	leadID := InsertUser(t, "lead", "password", 40)
+	InsertUser(t, "alice", "password", 0)
+	InsertUser(t, "bob", "password", 0)
+
+	rr, response := postBulk(t, leadToken(t, leadID),
+		`{"transfers":[{"toUser":"alice","amount":30},{"toUser":"bob","amount":20}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 when the total exceeds the balance")
+	assert.Equal(t, "Insufficient funds", response.Errors)
+	for _, result := range response.Results {
+		assert.Equal(t, domainAPI.BulkTransferNotSent, result.Status)
+	}
+	assert.Equal(t, 40, queryBalance(t, leadID))
+	assert.Equal(t, 0, countTransactions(t))
+}
+
+func TestSendCoinBulkRollsBackSentEntries(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	leadID := InsertUser(t, "lead", "password", 500)
+	aliceID := InsertUser(t, "alice", "password", 0)
+	InsertUser(t, "rich", "password", domain.MaxBalance-5)
+
+	rr, response := postBulk(t, leadToken(t, leadID),
+		`{"transfers":[{"toUser":"alice","amount":30},{"toUser":"rich","amount":10}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 when a recipient would pass the balance limit")
+	if assert.Len(t, response.Results, 2) {
+		assert.Equal(t, domainAPI.BulkTransferNotSent, response.Results[0].Status, "Earlier entries should be rolled back")
+		assert.Zero(t, response.Results[0].TransactionID)
+		assert.Equal(t, domainAPI.BulkTransferFailed, response.Results[1].Status)
+	}
+	assert.Equal(t, 500, queryBalance(t, leadID))
+	assert.Equal(t, 0, queryBalance(t, aliceID))
+	assert.Equal(t, 0, countTransactions(t))
+}
+
+func TestSendCoinBulkDatabaseErrorReportsEveryEntry(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	leadID := InsertUser(t, "lead", "password", 500)
+	InsertUser(t, "alice", "password", 0)
+	InsertUser(t, "bob", "password", 0)
+
+	// A constraint the second transfer cannot satisfy fails the batch with a database error.
+	_, err := Db.Connection.Exec(context.Background(),
+		"ALTER TABLE transactions ADD CONSTRAINT test_reject_amount CHECK (amount <> 13)")
+	if err != nil {
+		t.Fatalf("Failed to add constraint: %v", err)
+	}
+	defer func() {
+		_, _ = Db.Connection.Exec(context.Background(), "ALTER TABLE transactions DROP CONSTRAINT test_reject_amount")
+	}()
+
+	rr, response := postBulk(t, leadToken(t, leadID),
+		`{"transfers":[{"toUser":"alice","amount":30},{"toUser":"bob","amount":13}]}`)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "Internal server error", response.Errors)
+	assert.Nil(t, response.Coins, "The balance is only reported for a sent batch")
+	if assert.Len(t, response.Results, 2) {
+		for _, result := range response.Results {
+			assert.Equal(t, domainAPI.BulkTransferNotSent, result.Status)
+			assert.Zero(t, result.TransactionID)
+		}
+	}
+	assert.Equal(t, 500, queryBalance(t, leadID))
+	assert.Equal(t, 0, countTransactions(t))
+}
+
+func TestSendCoinBulkValidation(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	leadID := InsertUser(t, "lead", "password", 500)
+	InsertUser(t, "alice", "password", 0)
+	token := leadToken(t, leadID)
+
+	for _, body := range []string{
+		`{"transfers":[]}`,
+		`{"transfers":[{"toUser":"alice","amount":10},{"toUser":"alice","amount":5}]}`,
+		`{"transfers":[{"toUser":"alice","amount":0}]}`,
+		`{"transfers":[{"toUser":"","amount":5}]}`,
+	} {
+		rr, _ := postBulk(t, token, body)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for %s", body)
+	}
+	assert.Equal(t, 500, queryBalance(t, leadID))
+}
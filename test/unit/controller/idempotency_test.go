@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdempotentRouter() *chi.Mux {
+	cfg := &config.Config{SecretKey: SecretKey}
+	csController := &controller.CoinSender{
+		CoinSenderUsecase: usecase.NewCoinSender(repository.NewTransactionRepository(Db), 2*time.Second),
+		Cfg:               cfg,
+	}
+	buyController := &controller.Buy{
+		BuyUsecase: usecase.NewOrder(repository.NewOrderRepository(Db), 2*time.Second),
+		Cfg:        cfg,
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Use(authTokenMiddleware.Idempotency(repository.NewIdempotencyRepository(Db), time.Hour))
+	router.Post("/api/sendCoin", csController.CoinSender)
+	router.With(authTokenMiddleware.RequireRole(domain.RoleTeamLead, domain.RoleAdmin)).Post("/api/sendCoin/bulk", csController.Bulk)
+	router.Post("/api/buy/{merchName}", buyController.Buy)
+	return router
+}
+
+func postWithKey(t *testing.T, router *chi.Mux, path, token, key, body string) *httptest.ResponseRecorder {
+	req := NewRequest(t, http.MethodPost, path, token, body)
+	if key != "" {
+		req.Header.Set(authTokenMiddleware.IdempotencyKeyHeader, key)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSendCoinIdempotencyKeyReplays(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 500)
+	receiverID := InsertUser(t, "receiver", "password", 100)
+	token := CreateToken(t, senderID)
+	router := newIdempotentRouter()
+	body := `{"toUser":"receiver", "amount":100}`
+
+	rr := postWithKey(t, router, "/api/sendCoin", token, "transfer-1", body)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK on the first request")
+
+	rr = postWithKey(t, router, "/api/sendCoin", token, "transfer-1", body)
+	assert.Equal(t, http.StatusOK, rr.Code, "Retry should get the original status")
+	assert.Equal(t, "true", rr.Header().Get(authTokenMiddleware.IdempotentReplayedHeader), "Retry should be a replay")
+
+	assert.Equal(t, 400, queryBalance(t, senderID), "Sender should be charged once")
+	assert.Equal(t, 200, queryBalance(t, receiverID), "Receiver should be credited once")
+
+	rr = postWithKey(t, router, "/api/sendCoin", token, "transfer-1", `{"toUser":"receiver", "amount":50}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "Expected status 422 for a reused key")
+
+	rr = postWithKey(t, router, "/api/sendCoin", token, "", body)
+	assert.Equal(t, http.StatusOK, rr.Code, "Requests without a key should not be deduplicated")
+	assert.Equal(t, 300, queryBalance(t, senderID))
+}
+
+func TestBuyIdempotencyKeyReplaysErrors(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 100)
+	insertMerch(t, "cup", 20)
+	token := CreateToken(t, userID)
+	router := newIdempotentRouter()
+
+	rr := postWithKey(t, router, "/api/buy/cup", token, "purchase-1", "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK on purchase")
+
+	rr = postWithKey(t, router, "/api/buy/cup", token, "purchase-1", "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Retry should get the original status")
+	assert.Equal(t, 80, queryBalance(t, userID), "Purchase should be charged once")
+	assert.Equal(t, 1, countOrders(t, userID), "Only one order should be created")
+
+	rr = postWithKey(t, router, "/api/buy/unknown", token, "purchase-2", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = postWithKey(t, router, "/api/buy/unknown", token, "purchase-2", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Client errors should be replayed as well")
+	assert.JSONEq(t, `{"errors":"Merch does not exist"}`, rr.Body.String())
+}
+
+func TestIdempotencyKeysAreScopedToUser(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	firstID := InsertUser(t, "first", "password", 100)
+	secondID := InsertUser(t, "second", "password", 100)
+	insertMerch(t, "cup", 20)
+	router := newIdempotentRouter()
+
+	rr := postWithKey(t, router, "/api/buy/cup", CreateToken(t, firstID), "same-key", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = postWithKey(t, router, "/api/buy/cup", CreateToken(t, secondID), "same-key", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get(authTokenMiddleware.IdempotentReplayedHeader), "Another user's key must not be replayed")
+
+	var count int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT COUNT(*) FROM idempotency_keys").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query idempotency_keys: %v", err)
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	firstID := InsertUser(t, "first", "password", 100)
+	secondID := InsertUser(t, "second", "password", 100)
+	insertMerch(t, "cup", 20)
+	router := newIdempotentRouter()
+
+	postWithKey(t, router, "/api/buy/cup", CreateToken(t, firstID), "old-key", "")
+	postWithKey(t, router, "/api/buy/cup", CreateToken(t, secondID), "old-key", "")
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE idempotency_keys SET created_at = NOW() - INTERVAL '2 days'")
+	if err != nil {
+		t.Fatalf("Failed to age idempotency keys: %v", err)
+	}
+	postWithKey(t, router, "/api/buy/cup", CreateToken(t, firstID), "new-key", "")
+
+	deleted, err := repository.NewIdempotencyRepository(Db).DeleteExpired(context.Background(), time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted, "Expired keys of every user should be deleted")
+
+	var key string
+	err = Db.Connection.QueryRow(context.Background(), "SELECT key FROM idempotency_keys").Scan(&key)
+	if err != nil {
+		t.Fatalf("Failed to query idempotency_keys: %v", err)
+	}
+	assert.Equal(t, "new-key", key, "Fresh keys should be kept")
+}
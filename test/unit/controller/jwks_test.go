@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSigningKey(t *testing.T, id, algorithm string) *utility.SigningKey {
+	var privateKey interface{}
+	switch algorithm {
+	case "RS256":
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		privateKey = rsaKey
+	case "EdDSA":
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate Ed25519 key: %v", err)
+		}
+		privateKey = edKey
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+
+	key, err := utility.ParsePrivateKey(id, algorithm, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	return key
+}
+
+func newSignerRouter(signer domain.TokenSigner) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(authTokenMiddleware.Authorization(signer, repository.NewSessionRepository(Db)), authTokenMiddleware.RequireAuth)
+	router.Get("/api/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return router
+}
+
+func createSignedToken(t *testing.T, signer domain.TokenSigner, userID int) string {
+	_, refreshTokenHash, err := utility.CreateRefreshToken()
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	session, err := repository.NewSessionRepository(Db).Create(context.Background(), userID, refreshTokenHash, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	token, err := utility.CreateToken(signer, &domain.Principal{UserID: userID, TokenID: session.ID}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
+
+func ping(router *chi.Mux, token string) int {
+	req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestJWKSPublishesPublicKeys(t *testing.T) {
+	signer := utility.NewTokenSigner(
+		newSigningKey(t, "ed-2026", "EdDSA"),
+		newSigningKey(t, "rsa-2025", "RS256"),
+		utility.NewHMACKey(utility.DefaultKeyID, []byte(SecretKey)),
+	)
+	jwksController := &controller.JWKS{TokenSigner: signer}
+
+	router := chi.NewRouter()
+	router.Get("/.well-known/jwks.json", jwksController.Keys)
+
+	req, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var jwks domain.JWKS
+	if err := json.NewDecoder(rr.Body).Decode(&jwks); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	assert.Len(t, jwks.Keys, 2, "HMAC keys must not be published")
+	assert.Equal(t, "ed-2026", jwks.Keys[0].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.NotEmpty(t, jwks.Keys[0].X)
+	assert.Equal(t, "rsa-2025", jwks.Keys[1].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.NotEmpty(t, jwks.Keys[1].N)
+}
+
+func TestRotatedKeyKeepsVerifying(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 100)
+	oldKey := newSigningKey(t, "rsa-2025", "RS256")
+	newKey := newSigningKey(t, "ed-2026", "EdDSA")
+
+	oldToken := createSignedToken(t, utility.NewTokenSigner(oldKey), userID)
+
+	rotated := utility.NewTokenSigner(newKey, oldKey)
+	router := newSignerRouter(rotated)
+
+	assert.Equal(t, http.StatusOK, ping(router, oldToken), "Token signed by retired key should still verify")
+	assert.Equal(t, http.StatusOK, ping(router, createSignedToken(t, rotated, userID)), "Token signed by active key should verify")
+}
+
+func TestExpiredKeyRejected(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 100)
+	oldKey := newSigningKey(t, "rsa-2025", "RS256")
+
+	oldToken := createSignedToken(t, utility.NewTokenSigner(oldKey), userID)
+
+	oldKey.VerifyUntil = time.Now().Add(-time.Minute)
+	router := newSignerRouter(utility.NewTokenSigner(newSigningKey(t, "ed-2026", "EdDSA"), oldKey))
+
+	assert.Equal(t, http.StatusUnauthorized, ping(router, oldToken), "Token signed by expired key should be rejected")
+}
+
+func TestUnknownKeyRejected(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 100)
+	token := createSignedToken(t, utility.NewTokenSigner(newSigningKey(t, "rsa-other", "RS256")), userID)
+
+	router := newSignerRouter(TokenSigner)
+
+	assert.Equal(t, http.StatusUnauthorized, ping(router, token), "Token signed by unknown key should be rejected")
+}
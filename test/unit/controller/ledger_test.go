@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func reconcile(t *testing.T) *domain.LedgerReport {
+	report, err := repository.NewLedgerRepository(Db).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to reconcile ledger: %v", err)
+	}
+	return report
+}
+
+func registerUser(t *testing.T, username string) int {
+	rr := postAuth(t, newAuthRouter(&config.Config{}), "/api/auth/register", username, "password")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to register %s: %d %s", username, rr.Code, rr.Body.String())
+	}
+
+	var userID int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	if err != nil {
+		t.Fatalf("Failed to query user: %v", err)
+	}
+	return userID
+}
+
+func TestLedgerMatchesBalances(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	aliceID := registerUser(t, "alice")
+	bobID := registerUser(t, "bob")
+	insertMerch(t, "cup", 20)
+	aliceToken := CreateToken(t, aliceID)
+	bobToken := CreateToken(t, bobID)
+	coinRouter := newIdempotentRouter()
+	orderRouter := newOrderRouter()
+
+	rr := postWithKey(t, coinRouter, "/api/sendCoin", aliceToken, "", `{"toUser":"bob","amount":300}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for transfer")
+	rr = postWithKey(t, coinRouter, "/api/buy/cup", bobToken, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for purchase")
+	orderID := placeOrder(t, orderRouter, aliceToken, `{"items":[{"merch":"cup","quantity":3}]}`)
+	rr, _ = postOrderAction(t, orderRouter, aliceToken, fmt.Sprintf("/api/orders/%d/cancel", orderID))
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for cancellation")
+
+	report := reconcile(t)
+	assert.True(t, report.Consistent(), "Ledger should match balances: %+v", report)
+	assert.Equal(t, 2, report.CheckedUsers)
+
+	var kinds []string
+	rows, err := Db.Connection.Query(context.Background(), "SELECT kind FROM ledger_entries ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query ledger entries: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			t.Fatalf("Failed to scan ledger entry: %v", err)
+		}
+		kinds = append(kinds, kind)
+	}
+	assert.Equal(t, []string{
+		domain.LedgerKindGrant, domain.LedgerKindGrant, domain.LedgerKindTransfer,
+		domain.LedgerKindPurchase, domain.LedgerKindPurchase, domain.LedgerKindRefund,
+	}, kinds)
+}
+
+func TestLedgerReportsDrift(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	aliceID := registerUser(t, "alice")
+	registerUser(t, "bob")
+
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE users SET balance = balance + 5 WHERE id = $1", aliceID)
+	if err != nil {
+		t.Fatalf("Failed to update balance: %v", err)
+	}
+
+	report := reconcile(t)
+	assert.False(t, report.Consistent())
+	if assert.Len(t, report.Drifts, 1) {
+		assert.Equal(t, aliceID, report.Drifts[0].UserID)
+		assert.Equal(t, 5, report.Drifts[0].Balance-report.Drifts[0].LedgerBalance)
+	}
+	assert.Empty(t, report.UnbalancedEntries)
+}
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMerchRouter() *chi.Mux {
+	merchController := &controller.Merch{
+		MerchUsecase: usecase.NewMerch(repository.NewMerchRepository(Db), 2*time.Second),
+		Cfg:          &config.Config{SecretKey: SecretKey},
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware())
+	router.Get("/api/merch", merchController.Catalog)
+	router.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireAuth, authTokenMiddleware.RequireRole(domain.RoleAdmin))
+		r.Get("/api/admin/merch", merchController.List)
+		r.Post("/api/admin/merch", merchController.Create)
+		r.Put("/api/admin/merch/{id}", merchController.Update)
+		r.Delete("/api/admin/merch/{id}", merchController.Archive)
+	})
+	return router
+}
+
+func insertAdmin(t *testing.T) string {
+	adminID := InsertUser(t, "admin", "password", 0)
+	SetRoles(t, adminID, domain.RoleUser, domain.RoleAdmin)
+	return CreateToken(t, adminID)
+}
+
+func TestCatalogHidesArchivedMerch(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	insertMerch(t, "t-shirt", 80)
+	limitedID := insertMerch(t, "pen", 10)
+	archivedID := insertMerch(t, "cup", 20)
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE merch SET archived = TRUE WHERE id = $1", archivedID)
+	if err != nil {
+		t.Fatalf("Failed to archive merch: %v", err)
+	}
+	_, err = Db.Connection.Exec(context.Background(), "UPDATE merch SET stock = 3 WHERE id = $1", limitedID)
+	if err != nil {
+		t.Fatalf("Failed to set merch stock: %v", err)
+	}
+
+	rr := DoRequest(t, newMerchRouter(), http.MethodGet, "/api/merch", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Catalog should be public")
+
+	var catalog domainAPI.CatalogResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &catalog); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	stock := 3
+	assert.Equal(t, []domainAPI.CatalogItem{
+		{Name: "pen", Price: 10, Stock: &stock},
+		{Name: "t-shirt", Price: 80},
+	}, catalog.Items)
+}
+
+func TestAdminMerchLifecycle(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newMerchRouter()
+	token := insertAdmin(t)
+
+	rr := DoRequest(t, router, http.MethodPost, "/api/admin/merch", token, `{"name":"sticker","price":5,"stock":100}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status 201 Created")
+
+	var merch domain.Merch
+	if err := json.Unmarshal(rr.Body.Bytes(), &merch); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.Equal(t, "sticker", merch.Name)
+	assert.Equal(t, 5, merch.Price)
+	if assert.NotNil(t, merch.Stock) {
+		assert.Equal(t, 100, *merch.Stock)
+	}
+
+	rr = DoRequest(t, router, http.MethodPost, "/api/admin/merch", token, `{"name":"sticker","price":7}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 for a duplicate name")
+
+	path := "/api/admin/merch/" + strconv.Itoa(merch.ID)
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":15}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK on update")
+
+	rr = DoRequest(t, router, http.MethodDelete, path, token, "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK on archive")
+
+	var price int
+	var archived bool
+	err := Db.Connection.QueryRow(context.Background(),
+		"SELECT price, archived FROM merch WHERE id = $1", merch.ID).Scan(&price, &archived)
+	if err != nil {
+		t.Fatalf("Failed to query merch: %v", err)
+	}
+	assert.Equal(t, 15, price, "Price should be updated")
+	assert.True(t, archived, "Merch should be archived, not deleted")
+}
+
+func TestAdminMerchUpdateKeepsStock(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newMerchRouter()
+	token := insertAdmin(t)
+
+	rr := DoRequest(t, router, http.MethodPost, "/api/admin/merch", token, `{"name":"sticker","price":5,"stock":10}`)
+	var merch domain.Merch
+	if err := json.Unmarshal(rr.Body.Bytes(), &merch); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	path := "/api/admin/merch/" + strconv.Itoa(merch.ID)
+
+	// A purchase made after the admin read the item.
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE merch SET stock = stock - 3 WHERE id = $1", merch.ID)
+	if err != nil {
+		t.Fatalf("Failed to decrement stock: %v", err)
+	}
+
+	queryStock := func() *int {
+		var stock *int
+		err := Db.Connection.QueryRow(context.Background(), "SELECT stock FROM merch WHERE id = $1", merch.ID).Scan(&stock)
+		if err != nil {
+			t.Fatalf("Failed to query stock: %v", err)
+		}
+		return stock
+	}
+
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":6}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if stock := queryStock(); assert.NotNil(t, stock, "Changing the price should keep the limit") {
+		assert.Equal(t, 7, *stock, "Purchases should not be overwritten")
+	}
+
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":6,"stockDelta":5}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if stock := queryStock(); assert.NotNil(t, stock) {
+		assert.Equal(t, 12, *stock, "Restock should add to the current stock")
+	}
+
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":6,"stockDelta":-13}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 for a write-off below zero")
+
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":6,"stock":4,"stockDelta":1}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for two stock changes")
+
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":6,"unlimited":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, queryStock(), "The limit should be removed")
+
+	rr = DoRequest(t, router, http.MethodPut, path, token, `{"name":"sticker","price":6,"stockDelta":1}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 for a delta on an unlimited item")
+}
+
+func TestAdminMerchInvalidRequest(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	router := newMerchRouter()
+	token := insertAdmin(t)
+
+	rr := DoRequest(t, router, http.MethodPost, "/api/admin/merch", token, `{"name":"sticker","price":0}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for a non-positive price")
+
+	rr = DoRequest(t, router, http.MethodPost, "/api/admin/merch", token, `{"name":"sticker","price":5,"stock":-1}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for a negative stock")
+}
+
+func TestAdminMerchForbidden(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 0)
+
+	rr := DoRequest(t, newMerchRouter(), http.MethodPost, "/api/admin/merch", CreateToken(t, userID), `{"name":"sticker","price":5}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Expected status 403 for a non-admin")
+
+	rr = DoRequest(t, newMerchRouter(), http.MethodGet, "/api/admin/merch", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Expected status 401 without a token")
+}
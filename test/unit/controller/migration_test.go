@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/migration"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// baselineSchema is what scripts/database/setup.py created before the
+// schema moved to migrations.
+const baselineSchema = `
+    CREATE TABLE IF NOT EXISTS users (
+        id SERIAL PRIMARY KEY,
+        username VARCHAR(100) NOT NULL UNIQUE,
+        password TEXT NOT NULL,
+        balance INT NOT NULL DEFAULT 0 CHECK (balance >= 0 AND balance <= 100000000)
+    );
+    CREATE INDEX IF NOT EXISTS idx_users_username ON users (username);
+    CREATE INDEX IF NOT EXISTS idx_users_id ON users (id);
+
+    CREATE TABLE IF NOT EXISTS merch (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR(200) NOT NULL UNIQUE,
+        price INT NOT NULL CHECK (price > 0 AND price <= 100000000)
+    );
+    CREATE INDEX IF NOT EXISTS idx_merch_name ON merch (name);
+
+    CREATE TABLE IF NOT EXISTS transactions (
+        id SERIAL PRIMARY KEY,
+        sender INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        recipient INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        amount INT NOT NULL CHECK (amount > 0 AND amount <= 100000000)
+    );
+    CREATE INDEX IF NOT EXISTS idx_transactions_sender ON transactions (sender);
+    CREATE INDEX IF NOT EXISTS idx_transactions_recipient ON transactions (recipient);
+
+    CREATE TABLE IF NOT EXISTS merch_orders (
+        id SERIAL PRIMARY KEY,
+        owner INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        merch INT NOT NULL REFERENCES merch(id) ON DELETE CASCADE
+    );
+    CREATE INDEX IF NOT EXISTS idx_merch_orders_owner ON merch_orders (owner);
+
+    INSERT INTO merch (name, price) VALUES ('t-shirt', 80), ('cup', 20)
+    ON CONFLICT (name) DO NOTHING;
+`
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	ctx := context.Background()
+	const schema = "baseline_upgrade"
+
+	// The baseline database lives in its own schema, so the shared test tables stay untouched.
+	pool, err := pgxpool.New(ctx, DatabaseURL+"?search_path="+schema)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer pool.Close()
+	if _, err := pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE; CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+	}()
+
+	if _, err := pool.Exec(ctx, baselineSchema); err != nil {
+		t.Fatalf("Failed to create baseline schema: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+        INSERT INTO users (username, password, balance) VALUES ('alice', 'hash', 900), ('bob', 'hash', 1100);
+        INSERT INTO transactions (sender, recipient, amount) VALUES (1, 2, 100);
+        INSERT INTO merch_orders (owner, merch) VALUES (1, 2);
+    `)
+	if err != nil {
+		t.Fatalf("Failed to insert baseline data: %v", err)
+	}
+
+	migrator, err := migration.NewMigrator(&config.PostgresDb{Connection: pool})
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate baseline database: %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read migration status: %v", err)
+	}
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "Migration %d_%s should be applied", status.Version, status.Name)
+	}
+
+	var roles []string
+	var archived bool
+	err = pool.QueryRow(ctx, "SELECT roles FROM users WHERE username = 'alice'").Scan(&roles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user"}, roles, "Existing users should get the default role")
+	err = pool.QueryRow(ctx, "SELECT archived FROM merch WHERE name = 'cup'").Scan(&archived)
+	assert.NoError(t, err)
+	assert.False(t, archived)
+
+	var orderTotal, itemCount int
+	err = pool.QueryRow(ctx, "SELECT total_price FROM merch_orders WHERE id = 1").Scan(&orderTotal)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, orderTotal, "Existing orders should keep their price")
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM merch_order_items WHERE order_id = 1").Scan(&itemCount)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, itemCount)
+
+	// Transfers outlive deleted accounts after the migration.
+	_, err = pool.Exec(ctx, "DELETE FROM users WHERE username = 'bob'")
+	assert.NoError(t, err)
+	var recipient *int
+	err = pool.QueryRow(ctx, "SELECT recipient FROM transactions WHERE id = 1").Scan(&recipient)
+	assert.NoError(t, err)
+	assert.Nil(t, recipient, "The transfer should stay with an empty recipient")
+}
@@ -0,0 +1,482 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOrderRouter() *chi.Mux {
+	orderController := &controller.Orders{
+		OrderUsecase: usecase.NewOrders(repository.NewOrderRepository(Db), 2*time.Second),
+		Cfg: &config.Config{
+			SecretKey: SecretKey,
+			History:   config.History{PageSize: 20, MaxPageSize: 100},
+		},
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Get("/api/orders", orderController.List)
+	router.Post("/api/orders", orderController.PlaceOrder)
+	router.Post("/api/orders/{id}/cancel", orderController.Cancel)
+	router.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireRole(domain.RoleStoreManager, domain.RoleAdmin))
+		r.Get("/api/staff/orders", orderController.Pending)
+		r.Get("/api/staff/orders/{id}/events", orderController.Events)
+		r.Post("/api/staff/orders/{id}/pick", orderController.Pick)
+		r.Post("/api/staff/orders/{id}/deliver", orderController.Deliver)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(authTokenMiddleware.RequireRole(domain.RoleAdmin))
+		r.Post("/api/admin/orders/{id}/refund", orderController.Refund)
+	})
+	return router
+}
+
+func postOrder(t *testing.T, router *chi.Mux, token, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func placeOrder(t *testing.T, router *chi.Mux, token, body string) int {
+	rr := postOrder(t, router, token, body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to place order: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var response domainAPI.OrderResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response.OrderID
+}
+
+func postOrderAction(t *testing.T, router *chi.Mux, token, path string) (*httptest.ResponseRecorder, domainAPI.OrderDetails) {
+	return postOrderActionWithBody(t, router, token, path, "")
+}
+
+func postOrderActionWithBody(t *testing.T, router *chi.Mux, token, path, body string) (*httptest.ResponseRecorder, domainAPI.OrderDetails) {
+	rr := DoRequest(t, router, http.MethodPost, path, token, body)
+
+	var details domainAPI.OrderDetails
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &details); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return rr, details
+}
+
+func queryBalance(t *testing.T, userID int) int {
+	var balance int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if err != nil {
+		t.Fatalf("Failed to query user balance: %v", err)
+	}
+	return balance
+}
+
+func countOrders(t *testing.T, userID int) int {
+	var count int
+	err := Db.Connection.QueryRow(context.Background(), "SELECT COUNT(*) FROM merch_orders WHERE owner = $1", userID).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query merch_orders: %v", err)
+	}
+	return count
+}
+
+func TestPlaceOrderSuccess(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "socks", 10)
+	insertMerch(t, "cup", 20)
+
+	body := `{"items":[{"merch":"socks","quantity":10},{"merch":"cup","quantity":2},{"merch":"socks","quantity":1}]}`
+	rr := postOrder(t, newOrderRouter(), CreateToken(t, userID), body)
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status 201 Created")
+
+	var response domainAPI.OrderResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.NotZero(t, response.OrderID, "Order ID should be returned")
+	assert.WithinDuration(t, time.Now(), response.CreatedAt, time.Minute, "Creation time should be returned")
+	assert.Equal(t, 150, response.TotalCost, "Total cost should cover every unit")
+	assert.Equal(t, 850, response.Coins, "Remaining balance should be returned")
+	assert.Equal(t, 850, queryBalance(t, userID), "Balance should be debited once")
+
+	var lines, units int
+	err := Db.Connection.QueryRow(context.Background(),
+		"SELECT COUNT(*), SUM(quantity) FROM merch_order_items WHERE order_id = $1", response.OrderID).Scan(&lines, &units)
+	if err != nil {
+		t.Fatalf("Failed to query merch_order_items: %v", err)
+	}
+	assert.Equal(t, 2, lines, "Repeated merch should be merged into one line")
+	assert.Equal(t, 13, units)
+}
+
+func TestPlaceOrderIsAtomic(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "socks", 10)
+	limitedID := insertMerch(t, "cup", 20)
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE merch SET stock = 1 WHERE id = $1", limitedID)
+	if err != nil {
+		t.Fatalf("Failed to set merch stock: %v", err)
+	}
+	token := CreateToken(t, userID)
+	router := newOrderRouter()
+
+	rr := postOrder(t, router, token, `{"items":[{"merch":"socks","quantity":5},{"merch":"cup","quantity":2}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 when one item is out of stock")
+
+	rr = postOrder(t, router, token, `{"items":[{"merch":"socks","quantity":5},{"merch":"unknown","quantity":1}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 when one item does not exist")
+
+	rr = postOrder(t, router, token, `{"items":[{"merch":"socks","quantity":101}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for insufficient funds")
+
+	assert.Equal(t, 1000, queryBalance(t, userID), "Balance should remain unchanged")
+	assert.Equal(t, 0, countOrders(t, userID), "No order should be created")
+
+	var stock int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT stock FROM merch WHERE id = $1", limitedID).Scan(&stock)
+	if err != nil {
+		t.Fatalf("Failed to query merch stock: %v", err)
+	}
+	assert.Equal(t, 1, stock, "Stock should remain unchanged")
+}
+
+func TestPlaceOrderInvalidRequest(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "socks", 10)
+	token := CreateToken(t, userID)
+	router := newOrderRouter()
+
+	for _, body := range []string{
+		`{"items":[]}`,
+		`{"items":[{"merch":"socks","quantity":0}]}`,
+		`{"items":[{"merch":"","quantity":1}]}`,
+		`not json`,
+	} {
+		rr := postOrder(t, router, token, body)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for %s", body)
+	}
+
+	assert.Equal(t, 0, countOrders(t, userID), "No order should be created")
+}
+
+func TestListOrders(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	otherID := InsertUser(t, "other", "password", 1000)
+	insertMerch(t, "socks", 10)
+	insertMerch(t, "cup", 20)
+	router := newOrderRouter()
+	token := CreateToken(t, userID)
+
+	firstID := placeOrder(t, router, token, `{"items":[{"merch":"socks","quantity":2}]}`)
+	secondID := placeOrder(t, router, token, `{"items":[{"merch":"cup","quantity":1},{"merch":"socks","quantity":1}]}`)
+	placeOrder(t, router, CreateToken(t, otherID), `{"items":[{"merch":"cup","quantity":3}]}`)
+
+	rr := DoRequest(t, router, http.MethodGet, "/api/orders", token, "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+
+	var response domainAPI.OrdersResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if assert.Len(t, response.Orders, 2, "Only the user's orders should be listed") {
+		assert.Equal(t, secondID, response.Orders[0].OrderID, "Newest order should come first")
+		assert.Equal(t, domain.OrderStatusPlaced, response.Orders[0].Status)
+		assert.Equal(t, 30, response.Orders[0].TotalCost)
+		assert.Len(t, response.Orders[0].Items, 2)
+		assert.Equal(t, firstID, response.Orders[1].OrderID)
+		assert.Equal(t, []domainAPI.OrderItemDetails{{Merch: "socks", Quantity: 2, Price: 10}}, response.Orders[1].Items)
+	}
+}
+
+func TestCancelOrderRefundsAndRestocks(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	merchID := insertMerch(t, "cup", 20)
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE merch SET stock = 5 WHERE id = $1", merchID)
+	if err != nil {
+		t.Fatalf("Failed to set merch stock: %v", err)
+	}
+	router := newOrderRouter()
+	token := CreateToken(t, userID)
+
+	orderID := placeOrder(t, router, token, `{"items":[{"merch":"cup","quantity":3}]}`)
+	assert.Equal(t, 940, queryBalance(t, userID))
+
+	rr, details := postOrderAction(t, router, token, fmt.Sprintf("/api/orders/%d/cancel", orderID))
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+	assert.Equal(t, domain.OrderStatusCancelled, details.Status)
+	assert.Equal(t, 1000, queryBalance(t, userID), "Order price should be refunded")
+
+	var stock int
+	err = Db.Connection.QueryRow(context.Background(), "SELECT stock FROM merch WHERE id = $1", merchID).Scan(&stock)
+	if err != nil {
+		t.Fatalf("Failed to query merch stock: %v", err)
+	}
+	assert.Equal(t, 5, stock, "Items should be returned to stock")
+
+	var kind string
+	var amount int
+	err = Db.Connection.QueryRow(context.Background(),
+		"SELECT kind, amount FROM transactions WHERE order_id = $1 AND recipient = $2 AND sender IS NULL", orderID, userID).Scan(&kind, &amount)
+	if err != nil {
+		t.Fatalf("Failed to query refund transaction: %v", err)
+	}
+	assert.Equal(t, domain.TransactionKindRefund, kind)
+	assert.Equal(t, 60, amount)
+
+	_, profileRouter, _ := setupProfileController(t, userID)
+	profile := DoRequest(t, profileRouter, http.MethodGet, "/api/info", token, "")
+	var info domainAPI.ProfileResponse
+	if err := json.Unmarshal(profile.Body.Bytes(), &info); err != nil {
+		t.Fatalf("Failed to decode profile: %v", err)
+	}
+	assert.Empty(t, info.Inventory, "Cancelled orders should not count as inventory")
+	assert.Empty(t, info.CoinHistory.Received, "Refunds should not appear as transfers")
+
+	rr, _ = postOrderAction(t, router, token, fmt.Sprintf("/api/orders/%d/cancel", orderID))
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 when cancelling twice")
+	assert.Equal(t, 1000, queryBalance(t, userID), "Order should be refunded only once")
+}
+
+func TestCancelOrderRespectsBalanceLimit(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "cup", 20)
+	router := newOrderRouter()
+	token := CreateToken(t, userID)
+
+	orderID := placeOrder(t, router, token, `{"items":[{"merch":"cup","quantity":3}]}`)
+	_, err := Db.Connection.Exec(context.Background(), "UPDATE users SET balance = $1 WHERE id = $2", domain.MaxBalance-10, userID)
+	if err != nil {
+		t.Fatalf("Failed to set balance: %v", err)
+	}
+
+	rr, _ := postOrderAction(t, router, token, fmt.Sprintf("/api/orders/%d/cancel", orderID))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 when the refund exceeds the balance limit")
+	assert.Equal(t, domain.MaxBalance-10, queryBalance(t, userID), "Balance should stay unchanged")
+
+	var status string
+	err = Db.Connection.QueryRow(context.Background(), "SELECT status FROM merch_orders WHERE id = $1", orderID).Scan(&status)
+	if err != nil {
+		t.Fatalf("Failed to query order status: %v", err)
+	}
+	assert.Equal(t, domain.OrderStatusPlaced, status, "Order should stay placed")
+}
+
+func TestCancelOrderOfAnotherUser(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	ownerID := InsertUser(t, "buyer", "password", 1000)
+	otherID := InsertUser(t, "other", "password", 1000)
+	insertMerch(t, "cup", 20)
+	router := newOrderRouter()
+
+	orderID := placeOrder(t, router, CreateToken(t, ownerID), `{"items":[{"merch":"cup","quantity":1}]}`)
+
+	rr, _ := postOrderAction(t, router, CreateToken(t, otherID), fmt.Sprintf("/api/orders/%d/cancel", orderID))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status 404 for another user's order")
+
+	rr, _ = postOrderAction(t, router, CreateToken(t, otherID), "/api/orders/abc/cancel")
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for invalid order id")
+
+	assert.Equal(t, 980, queryBalance(t, ownerID), "Order should stay paid")
+	assert.Equal(t, 1000, queryBalance(t, otherID))
+}
+
+func insertStoreManager(t *testing.T) string {
+	managerID := InsertUser(t, "manager", "password", 0)
+	SetRoles(t, managerID, domain.RoleUser, domain.RoleStoreManager)
+	return CreateToken(t, managerID)
+}
+
+func TestStaffFulfilOrder(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "cup", 20)
+	router := newOrderRouter()
+	token := CreateToken(t, userID)
+	managerToken := insertStoreManager(t)
+
+	orderID := placeOrder(t, router, token, `{"items":[{"merch":"cup","quantity":2}]}`)
+	pickPath := fmt.Sprintf("/api/staff/orders/%d/pick", orderID)
+	deliverPath := fmt.Sprintf("/api/staff/orders/%d/deliver", orderID)
+
+	rr, _ := postOrderAction(t, router, token, pickPath)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Expected status 403 for a user without staff role")
+
+	rr = DoRequest(t, router, http.MethodGet, "/api/staff/orders", managerToken, "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+	var pending domainAPI.OrdersResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &pending); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if assert.Len(t, pending.Orders, 1) {
+		assert.Equal(t, orderID, pending.Orders[0].OrderID)
+		assert.Equal(t, "buyer", pending.Orders[0].Owner)
+	}
+
+	rr, _ = postOrderActionWithBody(t, router, managerToken, deliverPath, `{"location":"Office 3, desk 12"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 when delivering an unpicked order")
+
+	rr, details := postOrderAction(t, router, managerToken, pickPath)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+	assert.Equal(t, domain.OrderStatusPicked, details.Status)
+
+	rr, _ = postOrderActionWithBody(t, router, managerToken, deliverPath, `{"location":"   "}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for empty location")
+
+	rr, details = postOrderActionWithBody(t, router, managerToken, deliverPath, `{"location":" Office 3, desk 12 "}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+	assert.Equal(t, domain.OrderStatusFulfilled, details.Status)
+	assert.Equal(t, "Office 3, desk 12", details.DeliveryLocation)
+
+	rr = DoRequest(t, router, http.MethodGet, "/api/staff/orders", managerToken, "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &pending); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.Empty(t, pending.Orders, "Delivered orders should not be pending")
+
+	rr = DoRequest(t, router, http.MethodGet, fmt.Sprintf("/api/staff/orders/%d/events", orderID), managerToken, "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+	var trail domainAPI.OrderEventsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &trail); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if assert.Len(t, trail.Events, 3) {
+		assert.Equal(t, domain.OrderStatusPlaced, trail.Events[0].Status)
+		assert.Equal(t, "buyer", trail.Events[0].Actor)
+		assert.Equal(t, domain.OrderStatusPicked, trail.Events[1].Status)
+		assert.Equal(t, "manager", trail.Events[1].Actor)
+		assert.Equal(t, domain.OrderStatusFulfilled, trail.Events[2].Status)
+		assert.Equal(t, "Office 3, desk 12", trail.Events[2].Location)
+	}
+
+	rr = DoRequest(t, router, http.MethodGet, "/api/staff/orders/999999/events", managerToken, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status 404 for unknown order")
+}
+
+func TestPendingOrdersPagination(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "cup", 20)
+	router := newOrderRouter()
+	token := CreateToken(t, userID)
+	managerToken := insertStoreManager(t)
+
+	var placed []int
+	for i := 0; i < 3; i++ {
+		placed = append(placed, placeOrder(t, router, token, `{"items":[{"merch":"cup","quantity":1}]}`))
+	}
+
+	var seen []int
+	path := "/api/staff/orders?limit=2"
+	for page := 0; page < 2; page++ {
+		rr := DoRequest(t, router, http.MethodGet, path, managerToken, "")
+		assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+		var pending domainAPI.OrdersResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &pending); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		for _, order := range pending.Orders {
+			seen = append(seen, order.OrderID)
+		}
+		if page == 0 {
+			assert.NotEmpty(t, pending.NextCursor, "Expected a cursor for the first page")
+		} else {
+			assert.Empty(t, pending.NextCursor, "Last page should have no cursor")
+		}
+		path = "/api/staff/orders?limit=2&cursor=" + pending.NextCursor
+	}
+	assert.Equal(t, placed, seen, "Pages should walk the queue oldest first without gaps")
+
+	for _, query := range []string{"limit=0", "limit=101", "limit=abc", "cursor=bogus"} {
+		rr := DoRequest(t, router, http.MethodGet, "/api/staff/orders?"+query, managerToken, "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for %s", query)
+	}
+}
+
+func TestAdminRefundOrder(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "buyer", "password", 1000)
+	insertMerch(t, "cup", 20)
+	router := newOrderRouter()
+	token := CreateToken(t, userID)
+	managerToken := insertStoreManager(t)
+	adminToken := insertAdmin(t)
+
+	orderID := placeOrder(t, router, token, `{"items":[{"merch":"cup","quantity":2}]}`)
+	refundPath := fmt.Sprintf("/api/admin/orders/%d/refund", orderID)
+
+	rr, _ := postOrderAction(t, router, adminToken, refundPath)
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 when refunding an undelivered order")
+
+	rr, _ = postOrderAction(t, router, adminToken, fmt.Sprintf("/api/staff/orders/%d/pick", orderID))
+	assert.Equal(t, http.StatusOK, rr.Code, "Admins should be able to pick orders")
+	rr, _ = postOrderActionWithBody(t, router, adminToken, fmt.Sprintf("/api/staff/orders/%d/deliver", orderID), `{"location":"Reception"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Admins should be able to deliver orders")
+
+	rr, _ = postOrderAction(t, router, token, fmt.Sprintf("/api/orders/%d/cancel", orderID))
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status 409 when cancelling a fulfilled order")
+
+	rr, _ = postOrderAction(t, router, managerToken, refundPath)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Expected status 403 for store manager")
+
+	rr, details := postOrderAction(t, router, adminToken, refundPath)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+	assert.Equal(t, domain.OrderStatusRefunded, details.Status)
+	assert.Equal(t, 1000, queryBalance(t, userID), "Order price should be refunded")
+
+	rr, _ = postOrderAction(t, router, adminToken, "/api/admin/orders/999999/refund")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status 404 for unknown order")
+}
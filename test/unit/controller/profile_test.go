@@ -11,10 +11,10 @@ import (
 	"github.com/eslupmi101/avito_merch_store/api/controller"
 	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
 	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
 	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
 	"github.com/eslupmi101/avito_merch_store/internal/repository"
 	"github.com/eslupmi101/avito_merch_store/internal/usecase"
-	"github.com/eslupmi101/avito_merch_store/internal/utility"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,17 +48,23 @@ func insertMerchData(t *testing.T) {
 }
 
 func insertMerchOrder(t *testing.T, owner int, merchName string) {
-	var merchID int
+	var merchID, price int
 	err := Db.Connection.QueryRow(context.Background(),
-		"SELECT id FROM merch WHERE name = $1", merchName).Scan(&merchID)
+		"SELECT id, price FROM merch WHERE name = $1", merchName).Scan(&merchID, &price)
 	if err != nil {
 		t.Fatalf("Failed to get merch id for %s: %v", merchName, err)
 	}
-	_, err = Db.Connection.Exec(context.Background(),
-		"INSERT INTO merch_orders (owner, merch) VALUES ($1, $2)", owner, merchID)
+	var orderID int
+	err = Db.Connection.QueryRow(context.Background(),
+		"INSERT INTO merch_orders (owner, total_price) VALUES ($1, $2) RETURNING id", owner, price).Scan(&orderID)
 	if err != nil {
 		t.Fatalf("Failed to insert merch order: %v", err)
 	}
+	_, err = Db.Connection.Exec(context.Background(),
+		"INSERT INTO merch_order_items (order_id, merch, quantity, price) VALUES ($1, $2, 1, $3)", orderID, merchID, price)
+	if err != nil {
+		t.Fatalf("Failed to insert merch order item: %v", err)
+	}
 }
 
 func insertTransaction(t *testing.T, sender, recipient, amount int) {
@@ -70,21 +76,21 @@ func insertTransaction(t *testing.T, sender, recipient, amount int) {
 	}
 }
 
-func setupProfileController(userID int) (*controller.Profile, *chi.Mux, string) {
+func setupProfileController(t *testing.T, userID int) (*controller.Profile, *chi.Mux, string) {
 	or := repository.NewOrderRepository(Db)
 	mr := repository.NewMerchRepository(Db)
 	tr := repository.NewTransactionRepository(Db)
 	ur := repository.NewUserRepository(Db)
-	profileUsecase := usecase.NewProfile(or, mr, tr, ur, 2*time.Second)
+	profileUsecase := usecase.NewProfile(or, mr, tr, ur, 20, 2*time.Second)
 	cfg := &config.Config{SecretKey: "testsecret"}
 	prfController := &controller.Profile{
 		ProfileUsecase: profileUsecase,
 		Cfg:            cfg,
 	}
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/info", prfController.Profile)
-	token, _ := utility.CreateToken(userID, cfg.SecretKey)
+	token := CreateToken(t, userID)
 	return prfController, router, token
 }
 
@@ -97,13 +103,15 @@ func TestProfileInvalidToken(t *testing.T) {
 		Cfg:            cfg,
 	}
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/info", prfController.Profile)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer invalidtoken")
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"errors": "Unauthorized"}`, rr.Body.String())
 }
 
 func TestProfileNoToken(t *testing.T) {
@@ -115,7 +123,7 @@ func TestProfileNoToken(t *testing.T) {
 		Cfg:            cfg,
 	}
 	router := chi.NewRouter()
-	router.Use(authTokenMiddleware.Authorization(cfg.SecretKey))
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
 	router.Get("/api/info", prfController.Profile)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	rr := httptest.NewRecorder()
@@ -127,7 +135,7 @@ func TestProfileCleanUser(t *testing.T) {
 	Setup()
 	defer TearDown()
 	userID := InsertUser(t, "clean_user", "password", 0)
-	_, router, token := setupProfileController(userID)
+	_, router, token := setupProfileController(t, userID)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
@@ -148,7 +156,7 @@ func TestProfileUserWithBalance(t *testing.T) {
 	Setup()
 	defer TearDown()
 	userID := InsertUser(t, "rich_user", "password", 1000)
-	_, router, token := setupProfileController(userID)
+	_, router, token := setupProfileController(t, userID)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
@@ -168,7 +176,7 @@ func TestProfileUserWithInventory(t *testing.T) {
 	insertMerchData(t)
 	userID := InsertUser(t, "inventory_user", "password", 500)
 	insertMerchOrder(t, userID, "hoody")
-	_, router, token := setupProfileController(userID)
+	_, router, token := setupProfileController(t, userID)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
@@ -195,7 +203,7 @@ func TestProfileUserWithSentTransactions(t *testing.T) {
 	userID := InsertUser(t, "sender_user", "password", 800)
 	recipientID := InsertUser(t, "recipient_user", "password", 200)
 	insertTransaction(t, userID, recipientID, 150)
-	_, router, token := setupProfileController(userID)
+	_, router, token := setupProfileController(t, userID)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
@@ -208,6 +216,7 @@ func TestProfileUserWithSentTransactions(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Len(t, res.CoinHistory.Sent, 1)
 	assert.Equal(t, 150, res.CoinHistory.Sent[0].Amount)
+	assert.Equal(t, "recipient_user", res.CoinHistory.Sent[0].ToUser)
 }
 
 func TestProfileUserWithReceivedTransactions(t *testing.T) {
@@ -216,7 +225,7 @@ func TestProfileUserWithReceivedTransactions(t *testing.T) {
 	senderID := InsertUser(t, "sender_user", "password", 800)
 	userID := InsertUser(t, "receiver_user", "password", 300)
 	insertTransaction(t, senderID, userID, 200)
-	_, router, token := setupProfileController(userID)
+	_, router, token := setupProfileController(t, userID)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
@@ -229,6 +238,32 @@ func TestProfileUserWithReceivedTransactions(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Len(t, res.CoinHistory.Received, 1)
 	assert.Equal(t, 200, res.CoinHistory.Received[0].Amount)
+	assert.Equal(t, "sender_user", res.CoinHistory.Received[0].FromUser)
+}
+
+func TestProfileUserWithDeletedCounterpart(t *testing.T) {
+	Setup()
+	defer TearDown()
+	senderID := InsertUser(t, "sender_user", "password", 800)
+	userID := InsertUser(t, "receiver_user", "password", 300)
+	insertTransaction(t, senderID, userID, 200)
+	_, err := Db.Connection.Exec(context.Background(), "DELETE FROM users WHERE id = $1", senderID)
+	if err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	_, router, token := setupProfileController(t, userID)
+	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var res domainAPI.ProfileResponse
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, res.CoinHistory.Received, 1)
+	assert.Equal(t, domain.DeletedUsername, res.CoinHistory.Received[0].FromUser)
 }
 
 func TestProfileUserFullData(t *testing.T) {
@@ -240,7 +275,7 @@ func TestProfileUserFullData(t *testing.T) {
 	otherUserID := InsertUser(t, "other_user", "password", 500)
 	insertTransaction(t, userID, otherUserID, 100)
 	insertTransaction(t, otherUserID, userID, 50)
-	_, router, token := setupProfileController(userID)
+	_, router, token := setupProfileController(t, userID)
 	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
@@ -256,3 +291,67 @@ func TestProfileUserFullData(t *testing.T) {
 	assert.Len(t, res.CoinHistory.Sent, 1)
 	assert.Len(t, res.CoinHistory.Received, 1)
 }
+
+func TestProfileHistoryIsLimited(t *testing.T) {
+	Setup()
+	defer TearDown()
+	userID := InsertUser(t, "busy_user", "password", 1000)
+	otherUserID := InsertUser(t, "other_user", "password", 1000)
+	for i := 1; i <= 15; i++ {
+		insertTransaction(t, userID, otherUserID, i)
+		insertTransaction(t, otherUserID, userID, i)
+	}
+	_, router, token := setupProfileController(t, userID)
+	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var res domainAPI.ProfileResponse
+	err := json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, res.CoinHistory.Sent, 10, "Only the 20 most recent transfers should be shown")
+	assert.Len(t, res.CoinHistory.Received, 10)
+	assert.Equal(t, 15, res.CoinHistory.Sent[0].Amount, "Newest transfers should come first")
+}
+
+func TestProfileTimestamps(t *testing.T) {
+	Setup()
+	defer TearDown()
+	userID := InsertUser(t, "dated_user", "password", 1000)
+	otherUserID := InsertUser(t, "other_user", "password", 1000)
+	sentAt := time.Date(2026, 9, 30, 8, 15, 0, 0, time.UTC)
+	insertTransactionAt(t, userID, otherUserID, 10, sentAt)
+	insertTransactionAt(t, otherUserID, userID, 20, sentAt.Add(time.Hour))
+	_, router, token := setupProfileController(t, userID)
+	req, _ := http.NewRequest(http.MethodGet, "/api/info", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var raw struct {
+		RegisteredAt string `json:"registeredAt"`
+		CoinHistory  struct {
+			Received []struct {
+				CreatedAt string `json:"createdAt"`
+			} `json:"received"`
+			Sent []struct {
+				CreatedAt string `json:"createdAt"`
+			} `json:"sent"`
+		} `json:"coinHistory"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&raw); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	registeredAt, err := time.Parse(time.RFC3339, raw.RegisteredAt)
+	assert.NoError(t, err, "registeredAt should be RFC 3339")
+	assert.WithinDuration(t, time.Now(), registeredAt, time.Minute)
+	if assert.Len(t, raw.CoinHistory.Sent, 1) && assert.Len(t, raw.CoinHistory.Received, 1) {
+		assert.Equal(t, "2026-09-30T08:15:00Z", raw.CoinHistory.Sent[0].CreatedAt)
+		assert.Equal(t, "2026-09-30T09:15:00Z", raw.CoinHistory.Received[0].CreatedAt)
+	}
+}
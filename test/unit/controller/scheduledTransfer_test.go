@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newScheduledTransfersUsecase() domainAPI.ScheduledTransferUsecase {
+	return usecase.NewScheduledTransfers(repository.NewScheduledTransferRepository(Db), 100, 2*time.Second)
+}
+
+func newScheduledTransfersRouter() *chi.Mux {
+	scheduledTransfersController := &controller.ScheduledTransfers{
+		ScheduledTransferUsecase: newScheduledTransfersUsecase(),
+		Cfg:                      &config.Config{SecretKey: SecretKey},
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Post("/api/scheduledTransfers", scheduledTransfersController.Schedule)
+	router.Get("/api/scheduledTransfers", scheduledTransfersController.List)
+	router.Post("/api/scheduledTransfers/{id}/cancel", scheduledTransfersController.Cancel)
+	router.Get("/api/scheduledTransfers/{id}/runs", scheduledTransfersController.Runs)
+	return router
+}
+
+func scheduleTransfer(t *testing.T, router *chi.Mux, token, toUser string, amount int, runAt time.Time, repeat string) domainAPI.ScheduledTransferDetails {
+	body := fmt.Sprintf(`{"toUser":%q,"amount":%d,"runAt":%q,"repeat":%q}`, toUser, amount, runAt.Format(time.RFC3339), repeat)
+	rr := DoRequest(t, router, http.MethodPost, "/api/scheduledTransfers", token, body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to schedule transfer: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var details domainAPI.ScheduledTransferDetails
+	if err := json.Unmarshal(rr.Body.Bytes(), &details); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return details
+}
+
+func getScheduledRuns(t *testing.T, router *chi.Mux, token string, id int) []domainAPI.ScheduledTransferRun {
+	rr := DoRequest(t, router, http.MethodGet, fmt.Sprintf("/api/scheduledTransfers/%d/runs", id), token, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to list runs: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var response domainAPI.ScheduledTransferRunsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response.Runs
+}
+
+func runDue(t *testing.T, now time.Time) int {
+	executed, err := newScheduledTransfersUsecase().RunDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Failed to run scheduled transfers: %v", err)
+	}
+	return executed
+}
+
+func TestScheduledTransferRecurs(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	aliceID := registerUser(t, "alice")
+	bobID := registerUser(t, "bob")
+	aliceToken := CreateToken(t, aliceID)
+	router := newScheduledTransfersRouter()
+
+	runAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	details := scheduleTransfer(t, router, aliceToken, "bob", 50, runAt, domain.ScheduleRepeatWeekly)
+	assert.Equal(t, domain.ScheduledTransferStatusActive, details.Status)
+	assert.True(t, runAt.Equal(details.NextRunAt))
+
+	assert.Equal(t, 0, runDue(t, time.Now()), "Nothing should run before runAt")
+	assert.Equal(t, 1, runDue(t, runAt.Add(time.Minute)))
+	assert.Equal(t, 0, runDue(t, runAt.Add(time.Minute)), "A run should not repeat within the week")
+
+	assert.Equal(t, StartingBalance-50, queryBalance(t, aliceID))
+	assert.Equal(t, StartingBalance+50, queryBalance(t, bobID))
+
+	runs := getScheduledRuns(t, router, aliceToken, details.ID)
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, domain.ScheduledRunSucceeded, runs[0].Status)
+		assert.NotZero(t, runs[0].TransactionID)
+	}
+
+	rr := DoRequest(t, router, http.MethodGet, "/api/scheduledTransfers", aliceToken, "")
+	var list domainAPI.ScheduledTransfersResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if assert.Len(t, list.ScheduledTransfers, 1) {
+		assert.True(t, runAt.AddDate(0, 0, 7).Equal(list.ScheduledTransfers[0].NextRunAt), "Next run should be a week later")
+	}
+	assert.True(t, reconcile(t).Consistent(), "Scheduled transfers should be recorded in the ledger")
+}
+
+func TestScheduledTransferSkippedOnInsufficientFunds(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 10)
+	recipientID := InsertUser(t, "recipient", "password", 0)
+	token := CreateToken(t, senderID)
+	router := newScheduledTransfersRouter()
+
+	runAt := time.Now().Add(time.Hour)
+	details := scheduleTransfer(t, router, token, "recipient", 50, runAt, "")
+	assert.Equal(t, domain.ScheduleRepeatOnce, details.Repeat)
+
+	assert.Equal(t, 1, runDue(t, runAt.Add(time.Minute)))
+	assert.Equal(t, 10, queryBalance(t, senderID), "Balance should remain unchanged")
+	assert.Equal(t, 0, queryBalance(t, recipientID))
+
+	runs := getScheduledRuns(t, router, token, details.ID)
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, domain.ScheduledRunSkipped, runs[0].Status)
+		assert.Equal(t, domain.ErrInsufficientFunds.Error(), runs[0].Reason)
+	}
+
+	rr := DoRequest(t, router, http.MethodGet, "/api/scheduledTransfers", token, "")
+	assert.Contains(t, rr.Body.String(), `"status":"failed"`, "A skipped one-off transfer should fail")
+}
+
+func TestScheduledTransferFailureDoesNotBlockOthers(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 1000)
+	recipientID := InsertUser(t, "recipient", "password", 0)
+	token := CreateToken(t, senderID)
+	router := newScheduledTransfersRouter()
+
+	// A constraint the transfer cannot satisfy makes the first run fail with a database error.
+	_, err := Db.Connection.Exec(context.Background(),
+		"ALTER TABLE transactions ADD CONSTRAINT test_reject_amount CHECK (amount <> 13)")
+	if err != nil {
+		t.Fatalf("Failed to add constraint: %v", err)
+	}
+	defer func() {
+		_, _ = Db.Connection.Exec(context.Background(), "ALTER TABLE transactions DROP CONSTRAINT test_reject_amount")
+	}()
+
+	runAt := time.Now().Add(time.Hour)
+	failing := scheduleTransfer(t, router, token, "recipient", 13, runAt, domain.ScheduleRepeatWeekly)
+	succeeding := scheduleTransfer(t, router, token, "recipient", 20, runAt.Add(time.Second), "")
+
+	assert.Equal(t, 2, runDue(t, runAt.Add(time.Minute)), "The failed transfer should not stop the batch")
+	assert.Equal(t, 980, queryBalance(t, senderID))
+	assert.Equal(t, 20, queryBalance(t, recipientID))
+
+	runs := getScheduledRuns(t, router, token, failing.ID)
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, domain.ScheduledRunFailed, runs[0].Status)
+		assert.Contains(t, runs[0].Reason, "test_reject_amount")
+	}
+	runs = getScheduledRuns(t, router, token, succeeding.ID)
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, domain.ScheduledRunSucceeded, runs[0].Status)
+	}
+
+	assert.Equal(t, 0, runDue(t, runAt.Add(2*time.Minute)), "The failed transfer should wait for its next run")
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 1000)
+	otherID := InsertUser(t, "other", "password", 1000)
+	token := CreateToken(t, senderID)
+	router := newScheduledTransfersRouter()
+
+	runAt := time.Now().Add(time.Hour)
+	details := scheduleTransfer(t, router, token, "other", 50, runAt, domain.ScheduleRepeatMonthly)
+	path := fmt.Sprintf("/api/scheduledTransfers/%d/cancel", details.ID)
+
+	rr := DoRequest(t, router, http.MethodPost, path, CreateToken(t, otherID), "")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Only the sender should be able to cancel")
+
+	rr = DoRequest(t, router, http.MethodPost, path, token, "")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for cancellation")
+
+	rr = DoRequest(t, router, http.MethodPost, path, token, "")
+	assert.Equal(t, http.StatusConflict, rr.Code, "A cancelled transfer cannot be cancelled again")
+
+	assert.Equal(t, 0, runDue(t, runAt.Add(time.Minute)), "A cancelled transfer should not run")
+	assert.Equal(t, 1000, queryBalance(t, senderID))
+}
+
+func TestScheduleTransferValidation(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 1000)
+	InsertUser(t, "recipient", "password", 0)
+	token := CreateToken(t, senderID)
+	router := newScheduledTransfersRouter()
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	for body, status := range map[string]int{
+		`{"toUser":"recipient","amount":5,"runAt":"` + past + `"}`:                     http.StatusBadRequest,
+		`{"toUser":"recipient","amount":5,"runAt":"` + future + `","repeat":"daily"}`:  http.StatusBadRequest,
+		`{"toUser":"recipient","amount":0,"runAt":"` + future + `"}`:                   http.StatusBadRequest,
+		`{"toUser":"sender","amount":5,"runAt":"` + future + `"}`:                      http.StatusBadRequest,
+		`{"toUser":"ghost","amount":5,"runAt":"` + future + `"}`:                       http.StatusBadRequest,
+		`{"toUser":"recipient","amount":5,"runAt":"` + future + `","repeat":"weekly"}`: http.StatusCreated,
+	} {
+		rr := DoRequest(t, router, http.MethodPost, "/api/scheduledTransfers", token, body)
+		assert.Equal(t, status, rr.Code, "Unexpected status for %s", body)
+	}
+}
+
+func TestScheduledRunAtMonthly(t *testing.T) {
+	transfer := domain.ScheduledTransfer{
+		Repeat:  domain.ScheduleRepeatMonthly,
+		StartAt: time.Date(2026, time.January, 31, 9, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, time.Date(2026, time.February, 28, 9, 0, 0, 0, time.UTC), transfer.ScheduledRunAt(1))
+	assert.Equal(t, time.Date(2026, time.March, 31, 9, 0, 0, 0, time.UTC), transfer.ScheduledRunAt(2))
+	assert.Equal(t, time.Date(2027, time.January, 31, 9, 0, 0, 0, time.UTC), transfer.ScheduledRunAt(12))
+
+	transfer.Advance(time.Date(2026, time.April, 15, 0, 0, 0, 0, time.UTC), true)
+	assert.Equal(t, time.Date(2026, time.April, 30, 9, 0, 0, 0, time.UTC), transfer.NextRunAt,
+		"Occurrences missed while the service was down should not be caught up")
+}
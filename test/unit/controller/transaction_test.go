@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/api/controller"
+	authTokenMiddleware "github.com/eslupmi101/avito_merch_store/api/middleware"
+	"github.com/eslupmi101/avito_merch_store/internal/config"
+	"github.com/eslupmi101/avito_merch_store/internal/domain"
+	domainAPI "github.com/eslupmi101/avito_merch_store/internal/domain/api"
+	"github.com/eslupmi101/avito_merch_store/internal/repository"
+	"github.com/eslupmi101/avito_merch_store/internal/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransactionsRouter() *chi.Mux {
+	transactionsController := &controller.Transactions{
+		TransactionUsecase: usecase.NewTransactions(repository.NewTransactionRepository(Db), 2*time.Second),
+		Cfg: &config.Config{
+			SecretKey: SecretKey,
+			History:   config.History{PageSize: 20, MaxPageSize: 100},
+		},
+	}
+
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(), authTokenMiddleware.RequireAuth)
+	router.Get("/api/transactions", transactionsController.List)
+	router.Post("/api/transactions/{id}/reaction", transactionsController.React)
+	return router
+}
+
+func getTransactions(t *testing.T, router *chi.Mux, token string, query url.Values) (*httptest.ResponseRecorder, domainAPI.TransactionsResponse) {
+	req, err := http.NewRequest(http.MethodGet, "/api/transactions?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var response domainAPI.TransactionsResponse
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return rr, response
+}
+
+func insertTransactionAt(t *testing.T, sender, recipient, amount int, createdAt time.Time) {
+	_, err := Db.Connection.Exec(context.Background(),
+		"INSERT INTO transactions (sender, recipient, amount, created_at) VALUES ($1, $2, $3, $4)",
+		sender, recipient, amount, createdAt)
+	if err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+}
+
+func TestTransactionsPagination(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 1000)
+	otherID := InsertUser(t, "other", "password", 1000)
+	for amount := 1; amount <= 5; amount++ {
+		insertTransaction(t, userID, otherID, amount)
+	}
+	router := newTransactionsRouter()
+	token := CreateToken(t, userID)
+
+	var amounts []int
+	query := url.Values{"limit": {"2"}}
+	for page := 0; page < 3; page++ {
+		rr, response := getTransactions(t, router, token, query)
+		assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK")
+		for _, entry := range response.Transactions {
+			amounts = append(amounts, entry.Amount)
+			assert.Equal(t, domain.TransactionDirectionSent, entry.Direction)
+			assert.Equal(t, "other", entry.Counterparty)
+		}
+		if page < 2 {
+			assert.NotEmpty(t, response.NextCursor, "Expected a cursor for page %d", page)
+		} else {
+			assert.Empty(t, response.NextCursor, "Last page should have no cursor")
+		}
+		query.Set("cursor", response.NextCursor)
+	}
+
+	assert.Equal(t, []int{5, 4, 3, 2, 1}, amounts, "Pages should walk the history newest first without gaps")
+}
+
+func TestTransactionsFilters(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 1000)
+	aliceID := InsertUser(t, "alice", "password", 1000)
+	bobID := InsertUser(t, "bob", "password", 1000)
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	insertTransactionAt(t, userID, aliceID, 10, day)
+	insertTransactionAt(t, aliceID, userID, 20, day.AddDate(0, 0, 1))
+	insertTransactionAt(t, bobID, userID, 30, day.AddDate(0, 0, 2))
+	insertTransactionAt(t, aliceID, bobID, 40, day.AddDate(0, 0, 2))
+	router := newTransactionsRouter()
+	token := CreateToken(t, userID)
+
+	amountsFor := func(query url.Values) []int {
+		rr, response := getTransactions(t, router, token, query)
+		assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for %s", query.Encode())
+		amounts := []int{}
+		for _, entry := range response.Transactions {
+			amounts = append(amounts, entry.Amount)
+		}
+		return amounts
+	}
+
+	assert.Equal(t, []int{30, 20, 10}, amountsFor(url.Values{}), "Only the user's transfers should be listed")
+	assert.Equal(t, []int{10}, amountsFor(url.Values{"direction": {"sent"}}))
+	assert.Equal(t, []int{30, 20}, amountsFor(url.Values{"direction": {"received"}}))
+	assert.Equal(t, []int{20, 10}, amountsFor(url.Values{"counterparty": {"alice"}}))
+	assert.Equal(t, []int{20}, amountsFor(url.Values{"counterparty": {"alice"}, "direction": {"received"}}))
+	assert.Equal(t, []int{20}, amountsFor(url.Values{
+		"from": {day.Add(time.Hour).Format(time.RFC3339)},
+		"to":   {day.AddDate(0, 0, 2).Format(time.RFC3339)},
+	}), "Date range should include from and exclude to")
+	assert.Equal(t, []int{}, amountsFor(url.Values{"counterparty": {"nobody"}}))
+}
+
+func TestTransactionsInvalidQuery(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	userID := InsertUser(t, "user", "password", 1000)
+	router := newTransactionsRouter()
+	token := CreateToken(t, userID)
+
+	for _, query := range []url.Values{
+		{"direction": {"both"}},
+		{"limit": {"0"}},
+		{"limit": {"101"}},
+		{"limit": {"ten"}},
+		{"from": {"yesterday"}},
+		{"from": {"2026-10-02T00:00:00Z"}, "to": {"2026-10-01T00:00:00Z"}},
+		{"cursor": {"not a cursor"}},
+		{"counterparty": {"a b"}},
+	} {
+		rr, _ := getTransactions(t, router, token, query)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for %s", query.Encode())
+	}
+}
+
+func postReaction(t *testing.T, router *chi.Mux, token string, transactionID int, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/transactions/%d/reaction", transactionID), strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestTransactionReaction(t *testing.T) {
+	Setup()
+	defer TearDown()
+
+	senderID := InsertUser(t, "sender", "password", 1000)
+	recipientID := InsertUser(t, "recipient", "password", 0)
+	insertTransaction(t, senderID, recipientID, 10)
+	router := newTransactionsRouter()
+	senderToken := CreateToken(t, senderID)
+	recipientToken := CreateToken(t, recipientID)
+
+	rr := postReaction(t, router, senderToken, 1, `{"reaction":"heart"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Only the recipient should be able to react")
+
+	rr = postReaction(t, router, recipientToken, 1, `{"reaction":"angry"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status 400 for an unknown reaction")
+
+	rr = postReaction(t, router, recipientToken, 1, `{"reaction":"thanks"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK for the recipient")
+
+	_, response := getTransactions(t, router, senderToken, url.Values{})
+	if assert.Len(t, response.Transactions, 1) {
+		assert.Equal(t, domain.ReactionThanks, response.Transactions[0].Reaction, "The sender should see the reaction")
+	}
+
+	var stored string
+	err := Db.Connection.QueryRow(context.Background(),
+		"SELECT reaction FROM transaction_reactions WHERE transaction_id = 1 AND recipient_id = $1", recipientID).Scan(&stored)
+	assert.NoError(t, err, "Reaction should be stored apart from the transaction")
+	assert.Equal(t, domain.ReactionThanks, stored)
+
+	rr = postReaction(t, router, recipientToken, 1, `{"reaction":""}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status 200 OK when removing the reaction")
+
+	_, response = getTransactions(t, router, recipientToken, url.Values{})
+	if assert.Len(t, response.Transactions, 1) {
+		assert.Empty(t, response.Transactions[0].Reaction, "Reaction should be removed")
+	}
+}
@@ -0,0 +1,90 @@
+package utility
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eslupmi101/avito_merch_store/internal/utility"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// slowBackend records how many hashes run at the same time.
+type slowBackend struct {
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+func (b *slowBackend) track() {
+	active := b.active.Add(1)
+	for {
+		max := b.maxActive.Load()
+		if active <= max || b.maxActive.CompareAndSwap(max, active) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	b.active.Add(-1)
+}
+
+func (b *slowBackend) GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	b.track()
+	return password, nil
+}
+
+func (b *slowBackend) CompareHashAndPassword(hashedPassword, password []byte) error {
+	b.track()
+	return nil
+}
+
+func TestPasswordHasherLimitsConcurrency(t *testing.T) {
+	const workers = 3
+	backend := &slowBackend{}
+	hasher := utility.NewPasswordHasherWithBackend(bcrypt.MinCost, workers, backend)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := hasher.Hash(context.Background(), "password")
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := hasher.Compare(context.Background(), "password", "password")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(workers), backend.maxActive.Load(), "Hashes should run on exactly the configured number of workers")
+}
+
+func TestPasswordHasherWaitRespectsContext(t *testing.T) {
+	backend := &slowBackend{}
+	hasher := utility.NewPasswordHasherWithBackend(bcrypt.MinCost, 1, backend)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = hasher.Hash(context.Background(), "password")
+	}()
+	for backend.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := hasher.Hash(ctx, "password")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "A hash waiting for a busy worker should give up with its context")
+	<-done
+}